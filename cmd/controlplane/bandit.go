@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cost-aware-ml/pkg/decision"
+	"github.com/cost-aware-ml/pkg/store"
+)
+
+const banditFeedbackPoll = time.Minute
+
+// banditRewards remembers the reward the bandit was credited with for each
+// request, so feedback replaces it instead of adding to it.
+type banditRewards struct {
+	mu      sync.Mutex
+	credits map[string]credit
+}
+
+type credit struct {
+	context string
+	tier    decision.Tier
+	reward  float64
+	at      time.Time
+}
+
+func newBanditRewards() *banditRewards {
+	return &banditRewards{credits: make(map[string]credit)}
+}
+
+// credit records a reward Update just counted for requestID.
+func (r *banditRewards) credit(requestID, context string, tier decision.Tier, reward float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.credits[requestID] = credit{context: context, tier: tier, reward: reward, at: time.Now()}
+}
+
+// feedback applies the reward feedback earned requestID. A request this
+// process credited has that reward replaced; any other, whether served
+// before startup or by another replica, counts as a new pull. The request
+// is then credited with reward, so feedback posted again only corrects it.
+func (r *banditRewards) feedback(b *decision.Bandit, requestID, context string, tier decision.Tier, reward float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c, ok := r.credits[requestID]; ok {
+		b.Correct(c.context, c.tier, c.reward, reward)
+		c.reward = reward
+		r.credits[requestID] = c
+		return
+	}
+	b.Update(context, tier, reward)
+	r.credits[requestID] = credit{context: context, tier: tier, reward: reward, at: time.Now()}
+}
+
+// prune forgets rewards credited before the given time.
+func (r *banditRewards) prune(before time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, c := range r.credits {
+		if c.at.Before(before) {
+			delete(r.credits, id)
+		}
+	}
+}
+
+// runBanditFeedback folds ground-truth feedback into the bandit, replacing
+// the calibrated confidence each answer was first credited with. Arms are
+// keyed by the context the decision logged and rewards priced for the input
+// size it logged, so both match what the decision credited. Credits are
+// kept for calibrationWindow, the span feedback is replayed over at
+// startup.
+func runBanditFeedback(live *decision.LiveEngine, configStore *store.Store, rewards *banditRewards) {
+	after := time.Now().Add(-calibrationWindow)
+	ticker := time.NewTicker(banditFeedbackPoll)
+	for {
+		ctx := context.Background()
+		engine := live.Load()
+		traces, err := configStore.LoadLabeledTraces(ctx, decision.StrategyBandit, after)
+		if err != nil {
+			log.Printf("bandit: failed to load feedback: %v", err)
+		}
+
+		policies := make(map[string]*decision.Policy)
+		for _, t := range traces {
+			after = t.LabeledAt
+			if t.Context == "" || t.Correct == nil {
+				continue
+			}
+			policy, ok := policies[t.TenantID]
+			if !ok {
+				policy, err = configStore.LoadPolicy(ctx, t.TenantID)
+				if err != nil {
+					log.Printf("bandit: failed to load policy for tenant %s: %v", t.TenantID, err)
+				}
+				policies[t.TenantID] = policy
+			}
+
+			quality := 0.0
+			if *t.Correct {
+				quality = 1
+			}
+			reward := engine.RewardFor(policy, t.Input, quality, decision.TotalCost(t.Attempts))
+			rewards.feedback(engine.Bandit(), t.RequestID, t.Context, decision.Tier(t.Tier), reward)
+		}
+		rewards.prune(time.Now().Add(-calibrationWindow))
+		<-ticker.C
+	}
+}
+
+func banditHandler(live *decision.LiveEngine) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(live.Load().Bandit().Snapshot())
+	}
+}
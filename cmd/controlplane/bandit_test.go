@@ -0,0 +1,33 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cost-aware-ml/pkg/decision"
+)
+
+func TestBanditFeedbackCountsOnce(t *testing.T) {
+	b := decision.NewBandit(1)
+	rewards := newBanditRewards()
+	const ctx = "acme|normal|lt10"
+
+	// Served here: credited with its confidence, then corrected by
+	// feedback, posted twice with a changed label.
+	b.Update(ctx, decision.Tier0, 0.7)
+	rewards.credit("served", ctx, decision.Tier0, 0.7)
+	rewards.feedback(b, "served", ctx, decision.Tier0, 1)
+	rewards.feedback(b, "served", ctx, decision.Tier0, 0)
+
+	// Served elsewhere: a new pull the first time only.
+	rewards.feedback(b, "elsewhere", ctx, decision.Tier1, 1)
+	rewards.feedback(b, "elsewhere", ctx, decision.Tier1, 1)
+
+	arms := b.Snapshot()[ctx]
+	if a := arms[decision.Tier0]; a.Pulls != 1 || math.Abs(a.RewardSum) > 1e-9 {
+		t.Errorf("expected one tier0 pull rewarded 0, got %+v", a)
+	}
+	if a := arms[decision.Tier1]; a.Pulls != 1 || math.Abs(a.RewardSum-1) > 1e-9 {
+		t.Errorf("expected one tier1 pull rewarded 1, got %+v", a)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"time"
+
+	"github.com/cost-aware-ml/pkg/decision"
+	"github.com/cost-aware-ml/pkg/spend"
+)
+
+const reservationTTL = 2 * time.Minute
+
+// budgetGuard reserves the cost of each tier a request is about to call
+// against the tenant's monthly budget and settles the actual spend once the
+// cascade finishes. A nil ledger or zero limit disables reservations but
+// spend is still recorded. Every exit from the cascade settles, which also
+// drops the reservation.
+type budgetGuard struct {
+	ledger    *spend.Ledger
+	tenantID  string
+	requestID string
+	// reservationID keys the request's reservation; request IDs come from
+	// clients and may collide.
+	reservationID string
+	limit         float64
+	at            time.Time
+	spent         float64
+}
+
+func newBudgetGuard(ledger *spend.Ledger, tenantID, requestID string, at time.Time) *budgetGuard {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return &budgetGuard{ledger: ledger, tenantID: tenantID, requestID: requestID, reservationID: hex.EncodeToString(id), at: at}
+}
+
+func (g *budgetGuard) reserve(ctx context.Context, cents float64) bool {
+	if g.ledger == nil || g.limit <= 0 {
+		return true
+	}
+	err := g.ledger.Reserve(ctx, g.tenantID, g.reservationID, g.requestID, cents, g.limit, g.at)
+	if err == spend.ErrBudgetExhausted {
+		return false
+	}
+	if err != nil {
+		log.Printf("failed to reserve budget for tenant %s: %v", g.tenantID, err)
+	}
+	return true
+}
+
+func (g *budgetGuard) charge(cents float64) {
+	g.spent += cents
+}
+
+// settle records the spend even when the request's deadline has passed: the
+// tiers were called either way.
+func (g *budgetGuard) settle(ctx context.Context) {
+	if g.ledger == nil || g.tenantID == "" {
+		return
+	}
+	if err := g.ledger.Settle(context.WithoutCancel(ctx), g.tenantID, g.reservationID, g.spent, g.at); err != nil {
+		log.Printf("failed to settle spend for tenant %s: %v", g.tenantID, err)
+	}
+}
+
+// enterWithinBudget picks the request's entry tier and reserves its cost.
+// If the tenant's monthly budget cannot cover it, the request is marked
+// BudgetExhausted and entered again, which pins it to the cheapest tier it
+// can run on, and the second return is false. Either way req.EntryTier is
+// set to the tier entered at.
+func enterWithinBudget(engine *decision.Engine, req *decision.Request, telemetry decision.Telemetry, reserve func(cents float64) bool) (decision.Decision, bool) {
+	entry := engine.Entry(*req, telemetry)
+	reserved := entry.Tier == "" || reserve(entry.EstimatedCost)
+	if !reserved {
+		req.BudgetExhausted = true
+		entry = engine.Entry(*req, telemetry)
+	}
+	req.EntryTier = entry.Tier
+	return entry, reserved
+}
+
+// reserveParallel reserves every tier of a parallel cascade after the first,
+// whose cost is already reserved, and drops the tiers from the first one the
+// budget cannot cover.
+func reserveParallel(ctx context.Context, g *budgetGuard, req decision.Request, tiers []decision.TierConfig) []decision.Tier {
+	names := make([]decision.Tier, 0, len(tiers))
+	for i, t := range tiers {
+		if i > 0 && !g.reserve(ctx, decision.EstimateCost(t, req)) {
+			break
+		}
+		names = append(names, t.Name)
+	}
+	return names
+}
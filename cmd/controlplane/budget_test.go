@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/cost-aware-ml/pkg/decision"
+)
+
+func TestEnterWithinBudget(t *testing.T) {
+	engine := decision.NewEngine()
+	var reserved []float64
+	reserve := func(cents float64) bool {
+		reserved = append(reserved, cents)
+		return false
+	}
+
+	// Pre-routing would start at tier2, which the tenant can no longer pay for.
+	req := decision.Request{Budget: 10.0, EntryHint: decision.Tier2}
+	entry, ok := enterWithinBudget(engine, &req, decision.Telemetry{}, reserve)
+	if ok {
+		t.Fatal("expected the reservation to fail")
+	}
+	if entry.Tier != decision.Tier0 || !req.BudgetExhausted {
+		t.Errorf("expected a request over budget to enter at tier0 pinned, got %s (exhausted %v)", entry.Tier, req.BudgetExhausted)
+	}
+	if len(reserved) != 1 {
+		t.Errorf("expected one reservation attempt, got %v", reserved)
+	}
+
+	req = decision.Request{Budget: 10.0, EntryHint: decision.Tier2}
+	entry, ok = enterWithinBudget(engine, &req, decision.Telemetry{}, func(float64) bool { return true })
+	if !ok || entry.Tier != decision.Tier2 || req.BudgetExhausted {
+		t.Errorf("expected a reserved request to keep its tier2 entry, got %s", entry.Tier)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cost-aware-ml/pkg/calibration"
+	"github.com/cost-aware-ml/pkg/store"
+)
+
+const (
+	calibrationRefresh = 10 * time.Minute
+	calibrationWindow  = 7 * 24 * time.Hour
+)
+
+// refitCalibration fits per-tier maps from the tier calls graded by the
+// last calibrationWindow of feedback.
+func refitCalibration(ctx context.Context, calibrator *calibration.Calibrator, configStore *store.Store) error {
+	graded, err := configStore.LoadLabeledAttempts(ctx, time.Now().Add(-calibrationWindow))
+	if err != nil {
+		return err
+	}
+	samples := make(map[string][]calibration.Sample)
+	for _, a := range graded {
+		samples[a.Tier] = append(samples[a.Tier], calibration.Sample{Confidence: a.Confidence, Correct: a.Correct})
+	}
+	return calibrator.Refit(samples)
+}
+
+func runCalibration(calibrator *calibration.Calibrator, configStore *store.Store) {
+	ticker := time.NewTicker(calibrationRefresh)
+	for {
+		if err := refitCalibration(context.Background(), calibrator, configStore); err != nil {
+			log.Printf("failed to refit calibration: %v", err)
+		}
+		<-ticker.C
+	}
+}
+
+func calibrationHandler(calibrator *calibration.Calibrator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bins := 10
+		if b, err := strconv.Atoi(r.URL.Query().Get("bins")); err == nil && b > 0 {
+			bins = b
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(calibrator.Report(bins))
+	}
+}
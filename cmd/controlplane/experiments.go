@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cost-aware-ml/pkg/client"
+	"github.com/cost-aware-ml/pkg/decision"
+	"github.com/cost-aware-ml/pkg/experiment"
+	"github.com/cost-aware-ml/pkg/store"
+)
+
+// armConfig is what requests assigned to an experiment arm decide with.
+type armConfig struct {
+	experiment string
+	arm        string
+	// unit is the tenant or user ID the request was assigned by.
+	unit string
+	// engine is nil for arms that run on the live engine.
+	engine *decision.Engine
+	// policy is nil for arms that keep the tenant's policy.
+	policy *decision.Policy
+}
+
+// experimentSet is the enabled experiments with the engines of their arms,
+// rebuilt on every config reload.
+type experimentSet struct {
+	experiments []*experiment.Experiment
+	engines     map[string]map[string]*decision.Engine
+}
+
+// loadExperiments builds the arms of the enabled experiments on top of
+// engine, whose full tier config is tiers. Definitions that do not validate
+// are returned by name and left out, as are arms that point a tier at a
+// different worker than the live config or an earlier experiment: calls
+// are routed by tier name, so the arm's worker would take over the other's
+// traffic.
+func loadExperiments(ctx context.Context, configStore *store.Store, engine *decision.Engine, tiers []decision.TierConfig, caller *tierCaller) (*experimentSet, map[string]string, error) {
+	set := &experimentSet{engines: make(map[string]map[string]*decision.Engine)}
+	if configStore == nil {
+		return set, nil, nil
+	}
+	experiments, errs, err := experiment.Load(ctx, configStore, engine.ConfiguredTierNames())
+	if err != nil {
+		return nil, nil, err
+	}
+	workers := make(map[decision.Tier]string, len(tiers))
+	for _, t := range tiers {
+		workers[t.Name] = client.URLFor(string(t.Name), t.URL)
+	}
+	for _, e := range experiments {
+		if err := claimWorkers(e, workers); err != nil {
+			errs[e.Name] = err
+			continue
+		}
+		engines := make(map[string]*decision.Engine)
+		for _, a := range e.Arms {
+			if a.Tiers != nil {
+				caller.sync(a.Tiers)
+				engines[a.Name] = engine.WithTiers(a.Tiers)
+			}
+		}
+		set.experiments = append(set.experiments, e)
+		set.engines[e.Name] = engines
+	}
+	var invalid map[string]string
+	for name, err := range errs {
+		if invalid == nil {
+			invalid = make(map[string]string)
+		}
+		invalid[name] = err.Error()
+	}
+	return set, invalid, nil
+}
+
+// claimWorkers adds the workers e's arms call to workers, the URL each tier
+// name is already routed to, unless an arm routes a tier elsewhere.
+func claimWorkers(e *experiment.Experiment, workers map[decision.Tier]string) error {
+	claimed := make(map[decision.Tier]string)
+	for i, a := range e.Arms {
+		for j, t := range a.Tiers {
+			url := client.URLFor(string(t.Name), t.URL)
+			current, ok := claimed[t.Name]
+			if !ok {
+				current, ok = workers[t.Name]
+			}
+			if ok && current != url {
+				return &experiment.DefinitionError{Experiment: e.Name, Field: fmt.Sprintf("arms[%d].tiers[%d].url", i, j),
+					Message: fmt.Sprintf("tier %s is already served by %s", t.Name, current)}
+			}
+			claimed[t.Name] = url
+		}
+	}
+	for name, url := range claimed {
+		workers[name] = url
+	}
+	return nil
+}
+
+// assign enrolls a request in the first experiment that takes it.
+func (s *experimentSet) assign(tenantID, userID string) (armConfig, bool) {
+	if s == nil {
+		return armConfig{}, false
+	}
+	e, arm, ok := experiment.Pick(s.experiments, tenantID, userID)
+	if !ok {
+		return armConfig{}, false
+	}
+	return armConfig{experiment: e.Name, arm: arm.Name, unit: e.UnitID(tenantID, userID), engine: s.engines[e.Name][arm.Name], policy: arm.Policy}, true
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/cost-aware-ml/pkg/decision"
+	"github.com/cost-aware-ml/pkg/experiment"
+)
+
+func TestClaimWorkers(t *testing.T) {
+	workers := map[decision.Tier]string{decision.Tier0: "http://tier0-fast:8090"}
+	arm := func(url string) experiment.Arm {
+		return experiment.Arm{Name: "treatment", Tiers: []decision.TierConfig{
+			{Name: decision.Tier0, URL: "http://tier0-fast:8090"},
+			{Name: "tier0-distilled", URL: url},
+		}}
+	}
+
+	e := &experiment.Experiment{Name: "distilled", Arms: []experiment.Arm{{Name: "control"}, arm("http://distilled:8090")}}
+	if err := claimWorkers(e, workers); err != nil {
+		t.Fatalf("expected a new tier to be claimed, got %v", err)
+	}
+
+	// A live tier pointed at another worker would take over live traffic.
+	moved := &experiment.Experiment{Name: "moved", Arms: []experiment.Arm{{Name: "control"},
+		{Name: "treatment", Tiers: []decision.TierConfig{{Name: decision.Tier0, URL: "http://tier0-canary:8090"}}}}}
+	if err := claimWorkers(moved, workers); err == nil {
+		t.Error("expected an arm moving a live tier to be rejected")
+	}
+	// So would another experiment's tier of the same name.
+	other := &experiment.Experiment{Name: "other", Arms: []experiment.Arm{{Name: "control"}, arm("http://other:8090")}}
+	if err := claimWorkers(other, workers); err == nil {
+		t.Error("expected an arm moving another experiment's tier to be rejected")
+	}
+	if workers["tier0-distilled"] != "http://distilled:8090" {
+		t.Errorf("expected rejected arms to leave the claims alone, got %v", workers)
+	}
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cost-aware-ml/pkg/circuitbreaker"
+	"github.com/cost-aware-ml/pkg/client"
+	"github.com/cost-aware-ml/pkg/decision"
+)
+
+// tierCaller calls tier workers through their circuit breakers.
+type tierCaller struct {
+	mu              sync.RWMutex
+	urls            map[decision.Tier]string
+	clients         map[decision.Tier]*client.WorkerClient
+	circuitBreakers map[decision.Tier]*circuitbreaker.CircuitBreaker
+	queues          *tierQueues
+}
+
+func newTierCaller(tiers []decision.TierConfig) *tierCaller {
+	tc := &tierCaller{
+		urls:            make(map[decision.Tier]string),
+		clients:         make(map[decision.Tier]*client.WorkerClient),
+		circuitBreakers: make(map[decision.Tier]*circuitbreaker.CircuitBreaker),
+		queues:          newTierQueues(),
+	}
+	tc.sync(tiers)
+	return tc
+}
+
+// sync adds clients and breakers for tiers the caller has not seen and
+// repoints clients whose URL changed. Breakers keep their state across
+// reloads, and tiers dropped from the config keep theirs for calls still
+// in flight.
+func (tc *tierCaller) sync(tiers []decision.TierConfig) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	for _, t := range tiers {
+		url := client.URLFor(string(t.Name), t.URL)
+		if _, ok := tc.clients[t.Name]; !ok || tc.urls[t.Name] != url {
+			tc.clients[t.Name] = client.New(url)
+			tc.urls[t.Name] = url
+		}
+		if _, ok := tc.circuitBreakers[t.Name]; !ok {
+			tc.circuitBreakers[t.Name] = circuitbreaker.New(5, 3, 30*time.Second)
+		}
+	}
+}
+
+func (tc *tierCaller) lookup(tier decision.Tier) (*client.WorkerClient, *circuitbreaker.CircuitBreaker) {
+	tc.mu.RLock()
+	defer tc.mu.RUnlock()
+	return tc.clients[tier], tc.circuitBreakers[tier]
+}
+
+// breakerStates reports the state of every tier's circuit breaker.
+func (tc *tierCaller) breakerStates() map[decision.Tier]circuitbreaker.State {
+	tc.mu.RLock()
+	defer tc.mu.RUnlock()
+	states := make(map[decision.Tier]circuitbreaker.State, len(tc.circuitBreakers))
+	for tier, cb := range tc.circuitBreakers {
+		states[tier] = cb.State()
+	}
+	return states
+}
+
+type tierCall struct {
+	tier     decision.Tier
+	result   *client.InferResponse
+	err      error
+	canceled bool
+	refused  bool
+	latency  time.Duration
+}
+
+// outcome classifies the call for cost accounting.
+func (c tierCall) outcome() string {
+	switch {
+	case c.canceled:
+		return decision.OutcomeCanceled
+	case c.err == circuitbreaker.ErrCircuitOpen:
+		return decision.OutcomeCircuitOpen
+	case c.err != nil:
+		return decision.OutcomeError
+	}
+	return decision.OutcomeOK
+}
+
+func (tc *tierCaller) call(ctx context.Context, tier decision.Tier, req client.InferRequest) tierCall {
+	c := tierCall{tier: tier}
+	start := time.Now()
+	wc, cb := tc.lookup(tier)
+	tc.queues.enter(tier)
+	c.err = cb.Call(func() error {
+		var err error
+		c.result, err = wc.InferContext(ctx, req)
+		if err == client.ErrDeadlineExceeded {
+			// The worker refused a call it could not finish in time.
+			c.refused = true
+			return nil
+		}
+		if err != nil && ctx.Err() != nil {
+			// Abandoned by a hedge or cut off by the request's deadline,
+			// not a worker failure; keep it out of the breaker's failure
+			// count.
+			c.canceled = true
+			return nil
+		}
+		return err
+	})
+	tc.queues.leave(tier)
+	c.latency = time.Since(start)
+	tierCallDuration.WithLabelValues(string(tier)).Observe(c.latency.Seconds())
+	if c.canceled {
+		c.err = ctx.Err()
+	}
+	if c.refused {
+		c.err = client.ErrDeadlineExceeded
+	}
+	return c
+}
+
+// hedged calls primary and, if it has not answered within delay, hedge as
+// well. The first successful answer accept keeps wins and the other call is
+// canceled; an answer from hedge wins even if accept rejects it, since it
+// is where the cascade would escalate to anyway. If no answer wins that
+// way, the last successful call does, so a rejected primary answer is still
+// served when the hedge fails. It returns every call made, in completion
+// order, and the index of the winning one (-1 if all failed).
+func (tc *tierCaller) hedged(ctx context.Context, primary, hedge decision.Tier, delay time.Duration, req client.InferRequest, accept func(tierCall) bool) ([]tierCall, int) {
+	results := make(chan tierCall, 2)
+	cancels := make(map[decision.Tier]context.CancelFunc, 2)
+	start := func(tier decision.Tier) {
+		callCtx, cancel := context.WithCancel(ctx)
+		cancels[tier] = cancel
+		go func() { results <- tc.call(callCtx, tier, req) }()
+	}
+	defer func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}()
+
+	start(primary)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var calls []tierCall
+	select {
+	case c := <-results:
+		calls = append(calls, c)
+		if c.err != nil {
+			return calls, -1
+		}
+		return calls, 0
+	case <-timer.C:
+		start(hedge)
+	}
+
+	winner := -1
+	for pending := 2; pending > 0; pending-- {
+		c := <-results
+		calls = append(calls, c)
+		if c.err != nil || winner >= 0 {
+			continue
+		}
+		if c.tier == hedge || accept(c) || pending == 1 {
+			winner = len(calls) - 1
+			for tier, cancel := range cancels {
+				if tier != c.tier {
+					cancel()
+				}
+			}
+		}
+	}
+	if winner < 0 {
+		for i := len(calls) - 1; i >= 0; i-- {
+			if calls[i].err == nil {
+				return calls, i
+			}
+		}
+	}
+	return calls, winner
+}
+
+// parallel calls every tier at once, cheapest first in tiers. Once a tier's
+// answer is kept by accept, calls to more expensive tiers are canceled and
+// the cheapest kept answer wins as soon as every cheaper call has finished.
+// If no answer is kept the most expensive successful one wins. It returns
+// every call in completion order and the index of the winner (-1 if all
+// failed).
+func (tc *tierCaller) parallel(ctx context.Context, tiers []decision.Tier, req client.InferRequest, accept func(tierCall) bool) ([]tierCall, int) {
+	type indexed struct {
+		rank int
+		call tierCall
+	}
+	results := make(chan indexed, len(tiers))
+	cancels := make([]context.CancelFunc, len(tiers))
+	for i, tier := range tiers {
+		callCtx, cancel := context.WithCancel(ctx)
+		cancels[i] = cancel
+		go func(i int, tier decision.Tier) { results <- indexed{i, tc.call(callCtx, tier, req)} }(i, tier)
+	}
+	defer func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}()
+
+	calls := make([]tierCall, 0, len(tiers))
+	position := make([]int, len(tiers))
+	done := make([]bool, len(tiers))
+	kept := len(tiers)
+	for range tiers {
+		r := <-results
+		done[r.rank] = true
+		position[r.rank] = len(calls)
+		calls = append(calls, r.call)
+
+		if r.call.err == nil && r.rank < kept && accept(r.call) {
+			kept = r.rank
+			for j := kept + 1; j < len(tiers); j++ {
+				cancels[j]()
+			}
+		}
+		if kept < len(tiers) && allDone(done[:kept]) {
+			break
+		}
+	}
+
+	if kept < len(tiers) {
+		// Wait for the canceled calls so their cost is accounted for.
+		for pending := len(tiers) - len(calls); pending > 0; pending-- {
+			calls = append(calls, (<-results).call)
+		}
+		return calls, position[kept]
+	}
+	for rank := len(tiers) - 1; rank >= 0; rank-- {
+		if calls[position[rank]].err == nil {
+			return calls, position[rank]
+		}
+	}
+	return calls, -1
+}
+
+// failedOn reports whether a call to tier among calls failed.
+func failedOn(calls []tierCall, tier decision.Tier) bool {
+	for _, c := range calls {
+		if c.tier == tier && c.err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// ensembleVotes are the successful answers among calls other than the
+// winning one, in the order of tiers so the consensus does not depend on
+// which call finished first.
+func ensembleVotes(calls []tierCall, answer int, tiers []decision.Tier, calibrate func(string, float64) float64) []decision.Vote {
+	var votes []decision.Vote
+	for _, tier := range tiers {
+		for i, c := range calls {
+			if c.tier == tier && i != answer && c.err == nil {
+				votes = append(votes, decision.Vote{Tier: c.tier, Result: c.result.Result, Confidence: calibrate(string(c.tier), c.result.Confidence)})
+			}
+		}
+	}
+	return votes
+}
+
+func allDone(done []bool) bool {
+	for _, d := range done {
+		if !d {
+			return false
+		}
+	}
+	return true
+}
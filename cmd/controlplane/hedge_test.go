@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cost-aware-ml/pkg/client"
+	"github.com/cost-aware-ml/pkg/decision"
+)
+
+func worker(wait time.Duration, status int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(wait)
+		if status != http.StatusOK {
+			http.Error(w, "worker failed", status)
+			return
+		}
+		json.NewEncoder(w).Encode(client.InferResponse{Result: "answer", Confidence: 0.4})
+	}))
+}
+
+// answering is a worker that answers result with confidence.
+func answering(result string, confidence float64) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(client.InferResponse{Result: result, Confidence: confidence})
+	}))
+}
+
+func TestHedgedPrimaryRejectedHedgeFails(t *testing.T) {
+	primary := worker(20*time.Millisecond, http.StatusOK)
+	defer primary.Close()
+	hedge := worker(60*time.Millisecond, http.StatusInternalServerError)
+	defer hedge.Close()
+
+	tc := newTierCaller([]decision.TierConfig{
+		{Name: decision.Tier0, URL: primary.URL},
+		{Name: decision.Tier1, URL: hedge.URL},
+	})
+	reject := func(tierCall) bool { return false }
+	calls, winner := tc.hedged(context.Background(), decision.Tier0, decision.Tier1, 5*time.Millisecond,
+		client.InferRequest{RequestID: "r1", Input: "x"}, reject)
+
+	if len(calls) != 2 {
+		t.Fatalf("expected both tiers to be called, got %d calls", len(calls))
+	}
+	if winner < 0 {
+		t.Fatalf("expected the rejected primary answer to win when the hedge fails, got no winner")
+	}
+	if calls[winner].tier != decision.Tier0 || calls[winner].result == nil {
+		t.Errorf("expected tier0's answer to win, got %+v", calls[winner])
+	}
+	if !failedOn(calls, decision.Tier1) {
+		t.Errorf("expected the hedge call to be recorded as failed")
+	}
+}
+
+func TestHedgedAllFail(t *testing.T) {
+	primary := worker(20*time.Millisecond, http.StatusInternalServerError)
+	defer primary.Close()
+	hedge := worker(0, http.StatusInternalServerError)
+	defer hedge.Close()
+
+	tc := newTierCaller([]decision.TierConfig{
+		{Name: decision.Tier0, URL: primary.URL},
+		{Name: decision.Tier1, URL: hedge.URL},
+	})
+	accept := func(tierCall) bool { return true }
+	if _, winner := tc.hedged(context.Background(), decision.Tier0, decision.Tier1, 5*time.Millisecond,
+		client.InferRequest{RequestID: "r1", Input: "x"}, accept); winner != -1 {
+		t.Errorf("expected no winner when every call fails, got %d", winner)
+	}
+}
+
+func TestParallelEnsembleVotes(t *testing.T) {
+	// tier1 answers first; the votes still go in cascade order.
+	tier0 := worker(40*time.Millisecond, http.StatusOK)
+	defer tier0.Close()
+	tier1 := worker(0, http.StatusOK)
+	defer tier1.Close()
+	tier2 := worker(20*time.Millisecond, http.StatusOK)
+	defer tier2.Close()
+
+	tc := newTierCaller([]decision.TierConfig{
+		{Name: decision.Tier0, URL: tier0.URL},
+		{Name: decision.Tier1, URL: tier1.URL},
+		{Name: decision.Tier2, URL: tier2.URL},
+	})
+	tiers := []decision.Tier{decision.Tier0, decision.Tier1, decision.Tier2}
+	keepNone := func(tierCall) bool { return false }
+	calls, winner := tc.parallel(context.Background(), tiers, client.InferRequest{RequestID: "r1", Input: "x"}, keepNone)
+
+	if len(calls) != 3 {
+		t.Fatalf("expected every tier to answer, got %d calls", len(calls))
+	}
+	if winner < 0 || calls[winner].tier != decision.Tier2 {
+		t.Fatalf("expected the most expensive answer to win, got %d", winner)
+	}
+	identity := func(_ string, c float64) float64 { return c }
+	votes := ensembleVotes(calls, winner, tiers, identity)
+	if len(votes) != 2 || votes[0].Tier != decision.Tier0 || votes[1].Tier != decision.Tier1 {
+		t.Errorf("expected votes from tier0 then tier1, got %+v", votes)
+	}
+}
+
+func TestParallelEnsembleDisagreement(t *testing.T) {
+	tests := []struct {
+		name   string
+		tier1  string
+		tier   decision.Tier
+		reason string
+	}{
+		{"agree", "cat", decision.Tier1, "confidence_met"},
+		{"disagree", "dog", decision.Tier2, "escalated_disagreement"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tier0 := answering("cat", 0.8)
+			defer tier0.Close()
+			tier1 := answering(tt.tier1, 0.9)
+			defer tier1.Close()
+
+			tc := newTierCaller([]decision.TierConfig{
+				{Name: decision.Tier0, URL: tier0.URL},
+				{Name: decision.Tier1, URL: tier1.URL},
+			})
+			tiers := []decision.Tier{decision.Tier0, decision.Tier1}
+			keepNone := func(tierCall) bool { return false }
+			calls, winner := tc.parallel(context.Background(), tiers, client.InferRequest{RequestID: "r1", Input: "x"}, keepNone)
+			if winner < 0 {
+				t.Fatal("expected an answer")
+			}
+
+			identity := func(_ string, c float64) float64 { return c }
+			won := calls[winner]
+			votes := append(ensembleVotes(calls, winner, tiers, identity),
+				decision.Vote{Tier: won.tier, Result: won.result.Result, Confidence: won.result.Confidence})
+			req := decision.Request{Budget: 10, Policy: &decision.Policy{Strategy: decision.StrategyEnsemble}}
+			d, _ := decision.NewEngine().DecideEnsemble(won.tier, req, decision.Telemetry{}, votes)
+			if d.Tier != tt.tier || d.Reason != tt.reason {
+				t.Errorf("expected %s (%s), got %s (%s)", tt.tier, tt.reason, d.Tier, d.Reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/cost-aware-ml/pkg/decision"
+	"github.com/cost-aware-ml/pkg/latency"
+	"github.com/cost-aware-ml/pkg/store"
+)
+
+const (
+	latencyRefresh  = 10 * time.Minute
+	latencyWindow   = 7 * 24 * time.Hour
+	latencyQuantile = 0.99
+)
+
+// refitLatency fits per-tier latency models from the successful calls of
+// the last latencyWindow of requests.
+func refitLatency(ctx context.Context, predictor *latency.Predictor, configStore *store.Store) error {
+	traces, err := configStore.LoadTraces(ctx, "", time.Now().Add(-latencyWindow))
+	if err != nil {
+		return err
+	}
+	samples := make(map[string][]latency.Sample)
+	for _, t := range traces {
+		for _, a := range t.Attempts {
+			if a.Outcome != decision.OutcomeOK || a.LatencyMS <= 0 {
+				continue
+			}
+			samples[string(a.Tier)] = append(samples[string(a.Tier)], latency.Sample{
+				Tokens:    t.Input.Tokens,
+				Hour:      t.CreatedAt.UTC().Hour(),
+				LatencyMS: float64(a.LatencyMS),
+			})
+		}
+	}
+	predictor.Refit(samples)
+	return nil
+}
+
+func runLatencyModel(predictor *latency.Predictor, configStore *store.Store) {
+	ticker := time.NewTicker(latencyRefresh)
+	for {
+		if err := refitLatency(context.Background(), predictor, configStore); err != nil {
+			log.Printf("failed to refit latency model: %v", err)
+		}
+		<-ticker.C
+	}
+}
+
+// predictLatency fills telemetry with the P99 each modeled tier is expected
+// to take for req's input.
+func predictLatency(predictor *latency.Predictor, telemetry *decision.Telemetry, tiers []decision.Tier, req decision.Request, at time.Time) {
+	tokens := decision.Features(req.Input).Tokens
+	for _, tier := range tiers {
+		ms, ok := predictor.Predict(string(tier), tokens, at, latencyQuantile)
+		if !ok {
+			continue
+		}
+		if telemetry.PredictedLatencyMS == nil {
+			telemetry.PredictedLatencyMS = make(map[decision.Tier]int)
+		}
+		telemetry.PredictedLatencyMS[tier] = ms
+	}
+}
+
+func latencyHandler(predictor *latency.Predictor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(predictor.Report())
+	}
+}
@@ -1,20 +1,32 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
+	"github.com/cost-aware-ml/pkg/calibration"
 	"github.com/cost-aware-ml/pkg/circuitbreaker"
 	"github.com/cost-aware-ml/pkg/client"
+	"github.com/cost-aware-ml/pkg/deadline"
 	"github.com/cost-aware-ml/pkg/decision"
 	"github.com/cost-aware-ml/pkg/events"
+	"github.com/cost-aware-ml/pkg/latency"
 	"github.com/cost-aware-ml/pkg/observability"
+	"github.com/cost-aware-ml/pkg/prerouting"
+	"github.com/cost-aware-ml/pkg/shadow"
+	"github.com/cost-aware-ml/pkg/spend"
+	"github.com/cost-aware-ml/pkg/store"
 	"github.com/cost-aware-ml/pkg/telemetry"
+	_ "github.com/lib/pq"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.opentelemetry.io/otel"
@@ -38,6 +50,20 @@ var (
 			Buckets: prometheus.DefBuckets,
 		},
 	)
+	strategyDecisionsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "controlplane_strategy_decisions_total",
+			Help: "Total number of tier decisions by routing strategy",
+		},
+		[]string{"strategy", "tier"},
+	)
+	strategyCostCents = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "controlplane_strategy_cost_cents_total",
+			Help: "Total cost charged by routing strategy in cents",
+		},
+		[]string{"strategy"},
+	)
 	escalationsTotal = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "controlplane_escalations_total",
@@ -52,37 +78,147 @@ var (
 		},
 		[]string{"tier"},
 	)
+	tierCallDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "controlplane_tier_call_duration_seconds",
+			Help:    "Worker call duration per tier in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"tier"},
+	)
+	hedgesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "controlplane_hedges_total",
+			Help: "Total hedged calls by the tier that won",
+		},
+		[]string{"from", "to", "winner"},
+	)
+	parallelCascadesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "controlplane_parallel_cascades_total",
+			Help: "Total parallel cascades by the tier that won",
+		},
+		[]string{"winner"},
+	)
+	tierQueueDepth = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "controlplane_tier_queue_depth",
+			Help: "Worker calls in flight per tier",
+		},
+		[]string{"tier"},
+	)
+	policyErrorsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "controlplane_policy_errors_total",
+			Help: "Total number of tenant policies that failed to load or validate",
+		},
+		[]string{"tenant"},
+	)
+	budgetExhaustedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "controlplane_budget_exhausted_total",
+			Help: "Total requests received after the tenant's monthly budget was spent",
+		},
+		[]string{"tenant", "action"},
+	)
+	prerouteTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "controlplane_preroute_total",
+			Help: "Total requests by pre-routing outcome (skipped, kept, explored, ignored)",
+		},
+		[]string{"outcome", "tier"},
+	)
+	prerouteSavingsCents = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "controlplane_preroute_savings_cents_total",
+			Help: "Expected cost saved by starting pre-routed requests above the bottom tier in cents",
+		},
+	)
+	deadlineExceededTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "controlplane_deadline_exceeded_total",
+			Help: "Total requests whose deadline passed before a tier answered",
+		},
+		[]string{"tier"},
+	)
+	configReloadsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "controlplane_config_reloads_total",
+			Help: "Total engine config reloads by trigger and result (applied, unchanged, error)",
+		},
+		[]string{"trigger", "result"},
+	)
+	shadowDecisionsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "controlplane_shadow_decisions_total",
+			Help: "Total decisions replayed through the shadow candidate",
+		},
+		[]string{"tenant"},
+	)
+	shadowDisagreementsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "controlplane_shadow_disagreements_total",
+			Help: "Total decisions the shadow candidate made differently, by kind (tier, reason, cost) and the candidate's reason",
+		},
+		[]string{"tenant", "kind", "reason"},
+	)
+	experimentAssignmentsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "controlplane_experiment_assignments_total",
+			Help: "Total requests enrolled in each experiment arm (excluded when the tenant's policy does not fit the arm)",
+		},
+		[]string{"experiment", "arm"},
+	)
+	configVersion = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "controlplane_config_version",
+			Help: "Version of the engine config new decisions are made with",
+		},
+	)
 )
 
 func init() {
 	prometheus.MustRegister(decisionsTotal)
 	prometheus.MustRegister(decisionDuration)
+	prometheus.MustRegister(strategyDecisionsTotal)
+	prometheus.MustRegister(strategyCostCents)
 	prometheus.MustRegister(escalationsTotal)
 	prometheus.MustRegister(circuitBreakerState)
+	prometheus.MustRegister(tierQueueDepth)
+	prometheus.MustRegister(tierCallDuration)
+	prometheus.MustRegister(hedgesTotal)
+	prometheus.MustRegister(parallelCascadesTotal)
+	prometheus.MustRegister(policyErrorsTotal)
+	prometheus.MustRegister(budgetExhaustedTotal)
+	prometheus.MustRegister(deadlineExceededTotal)
+	prometheus.MustRegister(prerouteTotal)
+	prometheus.MustRegister(prerouteSavingsCents)
+	prometheus.MustRegister(configReloadsTotal)
+	prometheus.MustRegister(configVersion)
+	prometheus.MustRegister(experimentAssignmentsTotal)
+	prometheus.MustRegister(shadowDecisionsTotal)
+	prometheus.MustRegister(shadowDisagreementsTotal)
 }
 
-var tier0URL = os.Getenv("TIER0_URL")
-var tier1URL = os.Getenv("TIER1_URL")
-var tier2URL = os.Getenv("TIER2_URL")
+var dbURL = os.Getenv("DATABASE_URL")
 var natsURL = os.Getenv("NATS_URL")
 var prometheusURL = os.Getenv("PROMETHEUS_URL")
+var calibrationMethod = os.Getenv("CALIBRATION_METHOD")
+var tunerInterval = os.Getenv("TUNER_INTERVAL")
+var logLevel = os.Getenv("LOG_LEVEL")
+var engineConfig = os.Getenv("ENGINE_CONFIG")
+var shadowConfig = os.Getenv("SHADOW_CONFIG")
 
 func main() {
-	if tier0URL == "" {
-		tier0URL = "http://tier0-fast:8090"
-	}
-	if tier1URL == "" {
-		tier1URL = "http://tier1-mid:8091"
-	}
-	if tier2URL == "" {
-		tier2URL = "http://tier2-best:8092"
-	}
 	if natsURL == "" {
 		natsURL = "nats://nats:4222"
 	}
 	if prometheusURL == "" {
 		prometheusURL = "http://prometheus:9090"
 	}
+	if calibrationMethod == "" {
+		calibrationMethod = calibration.MethodIsotonic
+	}
 
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -103,26 +239,72 @@ func main() {
 		}
 	}
 
+	var configStore *store.Store
+	var ledger *spend.Ledger
+	if dbURL != "" {
+		db, err := sql.Open("postgres", dbURL)
+		if err != nil {
+			log.Printf("failed to connect to db: %v", err)
+		} else {
+			if err := db.Ping(); err != nil {
+				log.Printf("db ping failed: %v", err)
+			}
+			configStore = store.New(db)
+			ledger = spend.New(db, reservationTTL)
+		}
+	}
+
 	telemetryCollector := telemetry.NewCollector(prometheusURL)
 
-	engine := decision.NewEngine()
-	clients := map[decision.Tier]*client.WorkerClient{
-		decision.Tier0: client.New(tier0URL),
-		decision.Tier1: client.New(tier1URL),
-		decision.Tier2: client.New(tier2URL),
+	tiers := store.LoadTiersOrDefault(context.Background(), configStore)
+	live := decision.NewLiveEngine(tiers)
+	caller := newTierCaller(tiers)
+	configVersion.Set(1)
+
+	reloads := &reloader{live: live, caller: caller, configStore: configStore, path: engineConfig, shadowPath: shadowConfig}
+	shadowLog := shadow.NewLog(shadowSamples)
+	reloads.reloadLogged("startup")
+	if engineConfig != "" {
+		go reloads.watchFile()
+	}
+	go reloads.watchSignals()
+	if configStore != nil {
+		go reloads.listen(dbURL)
+	}
+
+	calibrator := calibration.NewCalibrator(calibrationMethod, 50)
+	if configStore != nil {
+		go runCalibration(calibrator, configStore)
+	}
+
+	predictor := latency.NewPredictor(50)
+	if configStore != nil {
+		go runLatencyModel(predictor, configStore)
+	}
+
+	router := prerouting.NewRouter(100)
+	if configStore != nil {
+		go runPrerouting(router, live, configStore)
+	}
+
+	if tunerInterval != "" && configStore != nil {
+		interval, err := time.ParseDuration(tunerInterval)
+		if err != nil {
+			log.Printf("invalid TUNER_INTERVAL %q: %v (tuner disabled)", tunerInterval, err)
+		} else {
+			go runTuner(interval, live, configStore)
+		}
 	}
 
-	circuitBreakers := map[decision.Tier]*circuitbreaker.CircuitBreaker{
-		decision.Tier0: circuitbreaker.New(5, 3, 30*time.Second),
-		decision.Tier1: circuitbreaker.New(5, 3, 30*time.Second),
-		decision.Tier2: circuitbreaker.New(5, 3, 30*time.Second),
+	rewards := newBanditRewards()
+	if configStore != nil {
+		go runBanditFeedback(live, configStore, rewards)
 	}
 
 	go func() {
 		ticker := time.NewTicker(5 * time.Second)
 		for range ticker.C {
-			for tier, cb := range circuitBreakers {
-				state := cb.State()
+			for tier, state := range caller.breakerStates() {
 				circuitBreakerState.WithLabelValues(string(tier)).Set(float64(state))
 			}
 		}
@@ -134,6 +316,12 @@ func main() {
 	})
 
 	http.Handle("/metrics", promhttp.Handler())
+	http.HandleFunc("/calibration", calibrationHandler(calibrator))
+	http.HandleFunc("/bandit", banditHandler(live))
+	http.HandleFunc("/reload", reloadHandler(reloads))
+	http.HandleFunc("/shadow", shadowHandler(reloads, shadowLog))
+	http.HandleFunc("/latency", latencyHandler(predictor))
+	http.HandleFunc("/prerouting", preroutingHandler(router))
 
 	http.HandleFunc("/decide", func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -146,17 +334,24 @@ func main() {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
+		// The whole request decides on one config version, even if a
+		// reload swaps the engine meanwhile.
+		engine := live.Load()
 
 		body, _ := io.ReadAll(r.Body)
 		r.Body.Close()
 
 		var req map[string]interface{}
-		if err := json.Unmarshal(body, &req); err != nil {
+		err := json.Unmarshal(body, &req)
+		if err != nil {
 			http.Error(w, "invalid json", http.StatusBadRequest)
 			return
 		}
 
 		requestID, _ := req["request_id"].(string)
+		if requestID == "" {
+			requestID = fmt.Sprintf("req-%d", time.Now().UnixNano())
+		}
 		userID, _ := req["user_id"].(string)
 		tenantID, _ := req["tenant_id"].(string)
 		priority, _ := req["priority"].(string)
@@ -170,6 +365,55 @@ func main() {
 			maxCostCents = mc
 		}
 
+		// The gateway sends what is left of the request's latency budget;
+		// direct callers get their max_latency_ms from now.
+		var cancel context.CancelFunc
+		if r.Header.Get(deadline.Header) != "" {
+			ctx, cancel = deadline.FromHeader(ctx, r.Header)
+		} else {
+			ctx, cancel = deadline.WithBudget(ctx, maxLatencyMS)
+		}
+		defer cancel()
+
+		var policy *decision.Policy
+		if configStore != nil && tenantID != "" {
+			policy, err = configStore.LoadPolicy(ctx, tenantID)
+			if err == nil && policy != nil {
+				err = policy.Validate(engine.ConfiguredTierNames())
+			}
+			if err != nil {
+				policyErrorsTotal.WithLabelValues(tenantID).Inc()
+				if _, ok := err.(*decision.PolicyError); ok {
+					http.Error(w, fmt.Sprintf("tenant %s: %v", tenantID, err), http.StatusUnprocessableEntity)
+					return
+				}
+				log.Printf("failed to load policy for tenant %s: %v (using defaults)", tenantID, err)
+				policy = nil
+			}
+		}
+
+		// The shadow candidate is built on the live config and the tenant's
+		// policy, whichever arm serves the request.
+		liveEngine, tenantPolicy := engine, policy
+		arm, enrolled := reloads.experiments.Load().assign(tenantID, userID)
+		if enrolled {
+			armPolicy, armEngine := policy, engine
+			if arm.policy != nil {
+				armPolicy = arm.policy
+			}
+			if arm.engine != nil {
+				armEngine = arm.engine
+			}
+			if armPolicy != nil && armPolicy.Validate(armEngine.ConfiguredTierNames()) != nil {
+				// The tenant's policy names tiers the arm does not have.
+				enrolled = false
+				experimentAssignmentsTotal.WithLabelValues(arm.experiment, "excluded").Inc()
+			} else {
+				policy, engine = armPolicy, armEngine
+				experimentAssignmentsTotal.WithLabelValues(arm.experiment, arm.arm).Inc()
+			}
+		}
+
 		decisionReq := decision.Request{
 			RequestID:    requestID,
 			UserID:       userID,
@@ -179,9 +423,32 @@ func main() {
 			MaxLatencyMS: maxLatencyMS,
 			MaxCostCents: maxCostCents,
 			Budget:       budget,
+			Policy:       policy,
+		}
+		if d, ok := ctx.Deadline(); ok {
+			decisionReq.Deadline = d
 		}
 
-		telemetry, err := telemetryCollector.CollectTelemetry(ctx)
+		guard := newBudgetGuard(ledger, tenantID, requestID, start)
+		if configStore != nil && tenantID != "" {
+			tenant, err := configStore.LoadTenant(ctx, tenantID)
+			if err != nil {
+				log.Printf("failed to load tenant %s: %v", tenantID, err)
+			} else if tenant != nil {
+				decisionReq.DefaultBudget = tenant.PerRequestBudgetCentsDefault
+				if tenant.MonthlyBudgetCents > 0 && ledger != nil {
+					guard.limit = tenant.MonthlyBudgetCents
+					remaining, err := ledger.Remaining(ctx, tenantID, guard.limit, start)
+					if err != nil {
+						log.Printf("failed to read spend for tenant %s: %v", tenantID, err)
+					} else if remaining > 0 && decision.EffectiveBudget(decisionReq) > remaining {
+						decisionReq.Budget = remaining
+					}
+				}
+			}
+		}
+
+		telemetry, err := telemetryCollector.CollectTelemetry(ctx, engine.TierNames())
 		if err != nil {
 			log.Printf("failed to collect telemetry: %v (using empty telemetry)", err)
 			telemetry = decision.Telemetry{
@@ -190,124 +457,241 @@ func main() {
 				QueueDepth:   make(map[decision.Tier]int),
 			}
 		}
+		caller.queues.merge(&telemetry)
+		predictLatency(predictor, &telemetry, engine.TierNames(), decisionReq, start)
 
 		var finalResult map[string]interface{}
 		var finalTier decision.Tier
 		var finalReason string
+		var finalStrategy string
+
+		saving, prerouted := preroute(router, &decisionReq)
+		entry, reserved := enterWithinBudget(engine, &decisionReq, telemetry, func(cents float64) bool {
+			return guard.reserve(ctx, cents)
+		})
+		explanations := []*decision.Explanation{entry.Explanation}
+		if entry.Tier == "" {
+			http.Error(w, "no tiers enabled", http.StatusServiceUnavailable)
+			return
+		}
+		if !reserved {
+			if policy.RejectsWhenBudgetExhausted() {
+				budgetExhaustedTotal.WithLabelValues(tenantID, decision.BudgetExhaustedReject).Inc()
+				http.Error(w, fmt.Sprintf("tenant %s: monthly budget exhausted", tenantID), http.StatusPaymentRequired)
+				return
+			}
+			budgetExhaustedTotal.WithLabelValues(tenantID, decision.BudgetExhaustedDowngrade).Inc()
+		}
+		switch {
+		case entry.Reason == "prerouted":
+			prerouteTotal.WithLabelValues("skipped", string(entry.Tier)).Inc()
+			prerouteSavingsCents.Add(saving)
+			entry.Propensity = 1 - prerouteExplore
+		case prerouted == "hinted":
+			prerouteTotal.WithLabelValues("ignored", string(decisionReq.EntryHint)).Inc()
+		case prerouted == "explored":
+			prerouteTotal.WithLabelValues("explored", string(entry.Tier)).Inc()
+			entry.Propensity = prerouteExplore
+		case prerouted == "kept":
+			prerouteTotal.WithLabelValues("kept", string(entry.Tier)).Inc()
+		}
+		currentTier := entry.Tier
 
-		currentTier := decision.Tier0
-		var tier0Confidence float64
+		var attempts []decision.Attempt
+		var votes []decision.Vote
+		var consensus decision.Consensus
 
-		for i := 0; i < 3; i++ {
-			cb := circuitBreakers[currentTier]
-			workerClient := clients[currentTier]
+		inferReq := client.InferRequest{RequestID: requestID, Input: req["input"]}
+		hedged, parallel := false, false
+		ensemble := policy != nil && policy.Strategy == decision.StrategyEnsemble
 
-			var result *client.InferResponse
-			var err error
+		for {
+			if ctx.Err() != nil {
+				guard.settle(ctx)
+				deadlineExceeded(w, requestID, currentTier)
+				return
+			}
+			var calls []tierCall
+			answer := -1
+			accept := func(c tierCall) bool {
+				conf := calibrator.Calibrate(string(c.tier), c.result.Confidence)
+				return engine.DecideAt(c.tier, decisionReq, telemetry, conf).Tier == c.tier
+			}
+			if tiers := reserveParallel(ctx, guard, decisionReq, engine.Parallel(currentTier, decisionReq, telemetry)); len(tiers) > 1 {
+				keep := accept
+				if ensemble {
+					// The consensus is taken over every tier's answer, so
+					// none may win before the others have voted.
+					keep = func(tierCall) bool { return false }
+				}
+				calls, answer = caller.parallel(ctx, tiers, inferReq, keep)
+				parallel = true
+				winner := "none"
+				if answer >= 0 {
+					winner = string(calls[answer].tier)
+				}
+				parallelCascadesTotal.WithLabelValues(winner).Inc()
+			} else if plan, ok := engine.Hedge(currentTier, decisionReq, telemetry); ok && guard.reserve(ctx, decision.EstimateCost(plan.Next, decisionReq)) {
+				delay := time.Duration(plan.DelayMS) * time.Millisecond
+				calls, answer = caller.hedged(ctx, currentTier, plan.Next.Name, delay, inferReq, accept)
+				if len(calls) > 1 {
+					hedged = true
+					winner := "none"
+					if answer >= 0 {
+						winner = string(calls[answer].tier)
+					}
+					hedgesTotal.WithLabelValues(string(currentTier), string(plan.Next.Name), winner).Inc()
+				}
+			} else {
+				calls = []tierCall{caller.call(ctx, currentTier, inferReq)}
+				if calls[0].err == nil {
+					answer = 0
+				}
+			}
 
-			err = cb.Call(func() error {
-				var callErr error
-				result, callErr = workerClient.Infer(client.InferRequest{
-					RequestID: requestID,
-					Payload:   req["input"],
-				})
-				return callErr
-			})
+			for _, c := range calls {
+				cfg, _ := engine.Config(c.tier)
+				cost := decision.AttemptCost(cfg, decisionReq, c.outcome())
+				guard.charge(cost)
+				attempt := decision.Attempt{
+					Tier:      c.tier,
+					Outcome:   c.outcome(),
+					LatencyMS: int(c.latency.Milliseconds()),
+					CostCents: cost,
+				}
+				if c.err == nil {
+					attempt.Confidence = c.result.Confidence
+					attempt.CalibratedConfidence = calibrator.Calibrate(string(c.tier), c.result.Confidence)
+					attempt.Result = c.result.Result
+				}
+				attempts = append(attempts, attempt)
+			}
 
-			if err != nil {
-				if err == circuitbreaker.ErrCircuitOpen {
-					if currentTier == decision.Tier0 {
-						currentTier = decision.Tier1
-						continue
-					}
-					if currentTier == decision.Tier1 {
-						currentTier = decision.Tier2
+			if answer < 0 {
+				failed := calls[len(calls)-1]
+				if errors.Is(failed.err, context.DeadlineExceeded) {
+					guard.settle(ctx)
+					deadlineExceeded(w, requestID, failed.tier)
+					return
+				}
+				if failed.err == circuitbreaker.ErrCircuitOpen {
+					if next, ok := engine.Next(failed.tier, policy); ok && guard.reserve(ctx, decision.EstimateCost(next, decisionReq)) {
+						currentTier = next.Name
 						continue
 					}
 				}
-				http.Error(w, fmt.Sprintf("tier %s error: %v", currentTier, err), http.StatusInternalServerError)
+				guard.settle(ctx)
+				http.Error(w, fmt.Sprintf("tier %s error: %v", failed.tier, failed.err), http.StatusInternalServerError)
 				return
 			}
 
-			if currentTier == decision.Tier0 {
-				tier0Confidence = result.Confidence
-				dec := engine.Decide(decisionReq, telemetry, tier0Confidence)
-
-				if dec.Tier == decision.Tier0 {
-					resultJSON, _ := json.Marshal(result)
-					json.Unmarshal(resultJSON, &finalResult)
-					finalResult["tier"] = string(decision.Tier0)
-					finalResult["reason"] = dec.Reason
-					finalResult["estimated_cost_cents"] = dec.EstimatedCost
-					finalTier = decision.Tier0
-					finalReason = dec.Reason
-					break
-				}
+			currentTier = calls[answer].tier
+			result := calls[answer].result
+			confidence := calibrator.Calibrate(string(currentTier), result.Confidence)
 
-				nextTier, _ := engine.Escalate(decision.Tier0, tier0Confidence, budget)
-				if nextTier != decision.Tier0 {
-					escalationsTotal.WithLabelValues(string(decision.Tier0), string(nextTier)).Inc()
-					currentTier = nextTier
-					continue
-				}
-
-				resultJSON, _ := json.Marshal(result)
-				json.Unmarshal(resultJSON, &finalResult)
-				finalResult["tier"] = string(decision.Tier0)
-				finalResult["reason"] = dec.Reason
-				finalResult["estimated_cost_cents"] = dec.EstimatedCost
-				finalTier = decision.Tier0
-				finalReason = dec.Reason
-				break
-			} else if currentTier == decision.Tier1 {
-				if tier0Confidence < 0.75 && budget >= 2.0 {
-					resultJSON, _ := json.Marshal(result)
-					json.Unmarshal(resultJSON, &finalResult)
-					finalResult["tier"] = string(decision.Tier1)
-					finalResult["reason"] = "escalated_from_tier0"
-					finalResult["estimated_cost_cents"] = 2.0
-					finalTier = decision.Tier1
-					finalReason = "escalated_from_tier0"
-					break
+			decide := func() decision.Decision {
+				if !ensemble {
+					return engine.DecideAt(currentTier, decisionReq, telemetry, confidence)
 				}
+				dec, c := engine.DecideEnsemble(currentTier, decisionReq, telemetry, votes)
+				consensus = c
+				return dec
+			}
+			if ensemble {
+				votes = append(votes, ensembleVotes(calls, answer, engine.TierNames(), calibrator.Calibrate)...)
+				votes = append(votes, decision.Vote{Tier: currentTier, Result: result.Result, Confidence: confidence})
+			}
 
-				nextTier, _ := engine.Escalate(decision.Tier1, result.Confidence, budget)
-				if nextTier == decision.Tier2 {
-					escalationsTotal.WithLabelValues(string(decision.Tier1), string(decision.Tier2)).Inc()
-					currentTier = decision.Tier2
+			dec := decide()
+			explanations = append(explanations, dec.Explanation)
+			if dec.Tier != currentTier && failedOn(calls, dec.Tier) {
+				// A hedge to the escalation target already failed on this
+				// request; serve the answer in hand rather than retry it.
+				dec.Tier, dec.Reason = currentTier, "escalation_target_failed"
+				dec.Explanation.Decision, dec.Explanation.Reason = dec.Tier, dec.Reason
+			}
+			if dec.Tier != currentTier {
+				if guard.reserve(ctx, dec.EstimatedCost) {
+					escalationsTotal.WithLabelValues(string(currentTier), string(dec.Tier)).Inc()
+					currentTier = dec.Tier
 					continue
 				}
+				budgetExhaustedTotal.WithLabelValues(tenantID, decision.BudgetExhaustedDowngrade).Inc()
+				decisionReq.BudgetExhausted = true
+				dec = decide()
+				explanations = append(explanations, dec.Explanation)
+			}
 
-				resultJSON, _ := json.Marshal(result)
-				json.Unmarshal(resultJSON, &finalResult)
-				finalResult["tier"] = string(decision.Tier1)
-				finalResult["reason"] = "escalated_from_tier0"
-				finalResult["estimated_cost_cents"] = 2.0
-				finalTier = decision.Tier1
-				finalReason = "escalated_from_tier0"
-				break
-			} else {
-				resultJSON, _ := json.Marshal(result)
-				json.Unmarshal(resultJSON, &finalResult)
-				finalResult["tier"] = string(decision.Tier2)
-				finalResult["reason"] = "escalated_to_tier2"
-				finalResult["estimated_cost_cents"] = 5.0
-				finalTier = decision.Tier2
-				finalReason = "escalated_to_tier2"
-				break
+			resultJSON, _ := json.Marshal(result)
+			json.Unmarshal(resultJSON, &finalResult)
+			if ensemble {
+				confidence = consensus.Confidence
+				finalResult["result"] = consensus.Result
+				finalResult["ensemble"] = consensus
 			}
+			finalResult["tier"] = string(currentTier)
+			finalResult["reason"] = dec.Reason
+			finalResult["estimated_cost_cents"] = decision.TotalCost(attempts)
+			finalResult["cost_breakdown"] = attempts
+			finalResult["strategy"] = dec.Strategy
+			finalResult["calibrated_confidence"] = confidence
+			finalResult["hedged"] = hedged
+			finalResult["parallel"] = parallel
+			finalResult["entry_tier"] = string(entry.Tier)
+			finalResult["propensity"] = entry.Propensity
+			finalResult["context"] = entry.Context
+			if entry.Strategy == decision.StrategyBandit {
+				reward := engine.Reward(decisionReq, confidence, decision.TotalCost(attempts))
+				engine.Bandit().Update(entry.Context, currentTier, reward)
+				rewards.credit(requestID, entry.Context, currentTier, reward)
+			}
+			finalTier = currentTier
+			finalReason = dec.Reason
+			finalStrategy = dec.Strategy
+			break
 		}
 
+		guard.settle(ctx)
+
 		duration := time.Since(start).Seconds()
 		decisionDuration.Observe(duration)
 		decisionsTotal.WithLabelValues(string(finalTier), finalReason).Inc()
+		strategyDecisionsTotal.WithLabelValues(finalStrategy, string(finalTier)).Inc()
+		strategyCostCents.WithLabelValues(finalStrategy).Add(decision.TotalCost(attempts))
 
 		traceID := trace.SpanFromContext(ctx).SpanContext().TraceID().String()
 		finalResult["trace_id"] = traceID
+		finalResult["config_version"] = engine.Version()
+		explanationJSON, _ := json.Marshal(explanations)
 		span.SetAttributes(
 			attribute.String("tier", string(finalTier)),
 			attribute.String("reason", finalReason),
+			attribute.String("strategy", finalStrategy),
+			attribute.Int64("config_version", engine.Version()),
+			attribute.String("explanation", string(explanationJSON)),
 		)
+		if enrolled {
+			finalResult["experiment"] = arm.experiment
+			finalResult["arm"] = arm.arm
+			finalResult["experiment_unit"] = arm.unit
+			span.SetAttributes(
+				attribute.String("experiment", arm.experiment),
+				attribute.String("arm", arm.arm),
+			)
+		}
+		if wantsExplanation(r, req) {
+			finalResult["explanation"] = explanations
+		}
+		if logLevel == "debug" {
+			log.Printf("debug: decision %s: %s", requestID, explanationJSON)
+		}
+
+		if candidate := reloads.shadow.Load(); candidate != nil {
+			served := shadow.Live(engine, decisionReq, finalTier, finalReason, attempts, hedged || parallel)
+			shadowReq := decisionReq
+			shadowReq.Policy = tenantPolicy
+			go candidate.run(liveEngine, shadowReq, telemetry, attempts, served, start, shadowLog)
+		}
 
 		if eventPublisher != nil {
 			confidence, _ := finalResult["confidence"].(float64)
@@ -318,10 +702,19 @@ func main() {
 				TenantID:      tenantID,
 				Tier:          string(finalTier),
 				Reason:        finalReason,
+				Strategy:      finalStrategy,
 				Budget:        budget,
 				EstimatedCost: finalResult["estimated_cost_cents"].(float64),
 				Confidence:    confidence,
 				LatencyMS:     int(latency),
+				Attempts:      attempts,
+				EntryTier:     string(entry.Tier),
+				Propensity:    entry.Propensity,
+				Context:       entry.Context,
+				ConfigVersion: engine.Version(),
+			}
+			if enrolled {
+				event.Experiment, event.Arm = arm.experiment, arm.arm
 			}
 			if err := eventPublisher.PublishDecision(ctx, event); err != nil {
 				log.Printf("failed to publish event: %v", err)
@@ -336,3 +729,24 @@ func main() {
 	log.Printf("controlplane listening on :%s", port)
 	log.Fatal(http.ListenAndServe(":"+port, nil))
 }
+
+// deadlineExceeded answers 504 once the request's latency budget has run out
+// while tier was next to answer.
+func deadlineExceeded(w http.ResponseWriter, requestID string, tier decision.Tier) {
+	deadlineExceededTotal.WithLabelValues(string(tier)).Inc()
+	http.Error(w, fmt.Sprintf("deadline exceeded: request %s ran out of its latency budget waiting for %s", requestID, tier), http.StatusGatewayTimeout)
+}
+
+// wantsExplanation reports whether the caller asked for the decision trace
+// with ?explain=true, an X-Explain header or "explain": true in the body.
+func wantsExplanation(r *http.Request, req map[string]interface{}) bool {
+	if explain, _ := req["explain"].(bool); explain {
+		return true
+	}
+	for _, v := range []string{r.URL.Query().Get("explain"), r.Header.Get("X-Explain")} {
+		if explain, err := strconv.ParseBool(v); err == nil && explain {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/cost-aware-ml/pkg/decision"
+	"github.com/cost-aware-ml/pkg/prerouting"
+	"github.com/cost-aware-ml/pkg/store"
+)
+
+const (
+	prerouteRefresh = 10 * time.Minute
+	prerouteWindow  = 7 * 24 * time.Hour
+	// prerouteExplore is the share of requests the router would skip that
+	// still start at the bottom tier, so it keeps seeing how those inputs
+	// fare there.
+	prerouteExplore = 0.05
+)
+
+// refitPrerouting fits the router from the last prerouteWindow of requests
+// that recorded their input size.
+func refitPrerouting(ctx context.Context, router *prerouting.Router, engine *decision.Engine, configStore *store.Store) error {
+	traces, err := configStore.LoadTraces(ctx, "", time.Now().Add(-prerouteWindow))
+	if err != nil {
+		return err
+	}
+	samples := make([]prerouting.Trace, 0, len(traces))
+	for _, t := range traces {
+		if t.Input.Bytes == 0 {
+			continue
+		}
+		samples = append(samples, prerouting.Trace{Input: t.Input, Attempts: t.Attempts})
+	}
+	router.Refit(engine.Cascade(nil), samples)
+	return nil
+}
+
+func runPrerouting(router *prerouting.Router, live *decision.LiveEngine, configStore *store.Store) {
+	ticker := time.NewTicker(prerouteRefresh)
+	for {
+		if err := refitPrerouting(context.Background(), router, live.Load(), configStore); err != nil {
+			log.Printf("failed to refit pre-routing: %v", err)
+		}
+		<-ticker.C
+	}
+}
+
+// preroute sets req's entry hint when the router expects its input to be
+// cheaper to start higher up, except for a prerouteExplore share of those
+// requests. It returns the expected saving and the outcome: "hinted",
+// "explored", "kept", or "" for bandit requests, which pick their own
+// entry.
+func preroute(router *prerouting.Router, req *decision.Request) (float64, string) {
+	if req.Policy != nil && req.Policy.Strategy == decision.StrategyBandit {
+		return 0, ""
+	}
+	tier, saving, ok := router.Route(decision.Features(req.Input))
+	if !ok {
+		return 0, "kept"
+	}
+	if rand.Float64() < prerouteExplore {
+		return 0, "explored"
+	}
+	req.EntryHint = tier
+	return saving, "hinted"
+}
+
+func preroutingHandler(router *prerouting.Router) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(router.Report())
+	}
+}
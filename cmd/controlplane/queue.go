@@ -0,0 +1,46 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/cost-aware-ml/pkg/decision"
+)
+
+// tierQueues counts this replica's worker calls in flight per tier. Prometheus
+// sums them across replicas for telemetry, but only as of the last scrape, so
+// the local count is used whenever it is higher.
+type tierQueues struct {
+	mu    sync.Mutex
+	depth map[decision.Tier]int
+}
+
+func newTierQueues() *tierQueues {
+	return &tierQueues{depth: make(map[decision.Tier]int)}
+}
+
+func (q *tierQueues) enter(tier decision.Tier) {
+	q.mu.Lock()
+	q.depth[tier]++
+	q.mu.Unlock()
+	tierQueueDepth.WithLabelValues(string(tier)).Inc()
+}
+
+func (q *tierQueues) leave(tier decision.Tier) {
+	q.mu.Lock()
+	q.depth[tier]--
+	q.mu.Unlock()
+	tierQueueDepth.WithLabelValues(string(tier)).Dec()
+}
+
+func (q *tierQueues) merge(telemetry *decision.Telemetry) {
+	if telemetry.QueueDepth == nil {
+		telemetry.QueueDepth = make(map[decision.Tier]int)
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for tier, depth := range q.depth {
+		if depth > telemetry.QueueDepth[tier] {
+			telemetry.QueueDepth[tier] = depth
+		}
+	}
+}
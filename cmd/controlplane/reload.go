@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/cost-aware-ml/pkg/decision"
+	"github.com/cost-aware-ml/pkg/store"
+	"github.com/lib/pq"
+)
+
+const (
+	configPoll     = 5 * time.Second
+	configChannel  = "config_changed"
+	reloadDeadline = 10 * time.Second
+)
+
+// reloader rebuilds the engine from its config source and swaps it in,
+// along with the experiments running on it and the shadow candidate. Tiers
+// come from the ENGINE_CONFIG file when one is set, otherwise from the
+// tiers table; the candidate comes from the SHADOW_CONFIG file.
+type reloader struct {
+	mu          sync.Mutex
+	live        *decision.LiveEngine
+	experiments atomic.Pointer[experimentSet]
+	shadow      atomic.Pointer[shadowCandidate]
+	caller      *tierCaller
+	configStore *store.Store
+	path        string
+	shadowPath  string
+}
+
+type reloadResult struct {
+	Version      int64             `json:"version"`
+	Changed      bool              `json:"changed"`
+	Source       string            `json:"source,omitempty"`
+	Tiers        []decision.Tier   `json:"tiers,omitempty"`
+	PolicyErrors map[string]string `json:"policy_errors,omitempty"`
+	Experiments  []string          `json:"experiments,omitempty"`
+	// ExperimentErrors lists experiments left out because their definition
+	// does not validate.
+	ExperimentErrors map[string]string `json:"experiment_errors,omitempty"`
+	Shadow           string            `json:"shadow,omitempty"`
+	// ShadowError is why the shadow candidate was not reloaded; the previous
+	// one keeps running.
+	ShadowError string `json:"shadow_error,omitempty"`
+	Error       string `json:"error,omitempty"`
+	Field       string `json:"field,omitempty"`
+}
+
+func (rl *reloader) load(ctx context.Context) ([]decision.TierConfig, string, error) {
+	if rl.path != "" {
+		data, err := os.ReadFile(rl.path)
+		if err != nil {
+			return nil, "file", err
+		}
+		cfg, err := decision.ParseConfig(data)
+		if err != nil {
+			return nil, "file", err
+		}
+		return cfg.Tiers, "file", nil
+	}
+	if rl.configStore == nil {
+		return decision.DefaultTiers(), "default", nil
+	}
+	tiers, err := rl.configStore.LoadTiers(ctx)
+	return tiers, "store", err
+}
+
+// reload loads the config and, if it is valid, installs it. Requests
+// already deciding keep the engine they loaded. Tenant policies that no
+// longer validate against the new tiers are reported but do not block the
+// reload; those requests are rejected as before until the policy is fixed.
+func (rl *reloader) reload(ctx context.Context, trigger string) (reloadResult, error) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	current := rl.live.Load()
+	result := reloadResult{Version: current.Version()}
+	tiers, source, err := rl.load(ctx)
+	result.Source = source
+	if err == nil {
+		err = decision.ValidateTiers(tiers)
+	}
+	if err != nil {
+		configReloadsTotal.WithLabelValues(trigger, "error").Inc()
+		return result, err
+	}
+
+	// Clients must exist before an engine that routes to them is visible.
+	rl.caller.sync(tiers)
+	engine, err := rl.live.Swap(tiers)
+	if err != nil {
+		configReloadsTotal.WithLabelValues(trigger, "error").Inc()
+		return result, err
+	}
+	result.Version = engine.Version()
+	result.Changed = engine != current
+	result.Tiers = engine.TierNames()
+	configVersion.Set(float64(result.Version))
+	if result.Changed {
+		configReloadsTotal.WithLabelValues(trigger, "applied").Inc()
+		log.Printf("engine config version %d loaded from %s (%s)", result.Version, source, trigger)
+	} else {
+		configReloadsTotal.WithLabelValues(trigger, "unchanged").Inc()
+	}
+
+	if rl.configStore != nil {
+		policies, err := rl.configStore.ListPolicies(ctx)
+		if err != nil {
+			log.Printf("failed to list policies: %v", err)
+		}
+		for tenant, p := range policies {
+			if err := p.Validate(engine.ConfiguredTierNames()); err != nil {
+				if result.PolicyErrors == nil {
+					result.PolicyErrors = make(map[string]string)
+				}
+				result.PolicyErrors[tenant] = err.Error()
+			}
+		}
+		for tenant, msg := range result.PolicyErrors {
+			log.Printf("policy for tenant %s is invalid under config version %d: %s", tenant, result.Version, msg)
+		}
+	}
+
+	set, invalid, err := loadExperiments(ctx, rl.configStore, engine, tiers, rl.caller)
+	if err != nil {
+		log.Printf("failed to load experiments: %v (keeping the running ones)", err)
+		set = rl.experiments.Load()
+	} else {
+		rl.experiments.Store(set)
+	}
+	if set != nil {
+		for _, e := range set.experiments {
+			result.Experiments = append(result.Experiments, e.Name)
+		}
+	}
+	result.ExperimentErrors = invalid
+	for name, msg := range invalid {
+		log.Printf("experiment %s disabled: %s", name, msg)
+	}
+
+	if rl.shadowPath != "" {
+		candidate, err := loadShadow(rl.shadowPath, engine)
+		if err != nil {
+			result.ShadowError = err.Error()
+			log.Printf("failed to load shadow candidate: %v", err)
+		} else {
+			rl.shadow.Store(candidate)
+		}
+	}
+	if c := rl.shadow.Load(); c != nil {
+		result.Shadow = c.name
+	}
+	return result, nil
+}
+
+func (rl *reloader) reloadLogged(trigger string) {
+	ctx, cancel := context.WithTimeout(context.Background(), reloadDeadline)
+	defer cancel()
+	if _, err := rl.reload(ctx, trigger); err != nil {
+		log.Printf("config reload (%s) failed, keeping version %d: %v", trigger, rl.live.Load().Version(), err)
+	}
+}
+
+// watchFile reloads when the config file's modification time changes.
+func (rl *reloader) watchFile() {
+	var last time.Time
+	if info, err := os.Stat(rl.path); err == nil {
+		last = info.ModTime()
+	}
+	ticker := time.NewTicker(configPoll)
+	for range ticker.C {
+		info, err := os.Stat(rl.path)
+		if err != nil || info.ModTime().Equal(last) {
+			continue
+		}
+		last = info.ModTime()
+		rl.reloadLogged("file")
+	}
+}
+
+func (rl *reloader) watchSignals() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	for range signals {
+		rl.reloadLogged("sighup")
+	}
+}
+
+// listen reloads on the notifications the tiers, policies and experiments
+// triggers send on configChannel, and after every reconnect in case one was
+// missed.
+func (rl *reloader) listen(dbURL string) {
+	listener := pq.NewListener(dbURL, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("config listener: %v", err)
+		}
+	})
+	if err := listener.Listen(configChannel); err != nil {
+		log.Printf("failed to listen on %s: %v (config reloads on notify disabled)", configChannel, err)
+		listener.Close()
+		return
+	}
+	for range listener.Notify {
+		rl.reloadLogged("notify")
+	}
+}
+
+func reloadHandler(rl *reloader) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		result, err := rl.reload(r.Context(), "endpoint")
+		w.Header().Set("Content-Type", "application/json")
+		if err != nil {
+			result.Error = err.Error()
+			var cfgErr *decision.ConfigError
+			if errors.As(err, &cfgErr) {
+				result.Field = cfgErr.Field
+				w.WriteHeader(http.StatusUnprocessableEntity)
+			} else {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}
+		json.NewEncoder(w).Encode(result)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/cost-aware-ml/pkg/decision"
+	"github.com/cost-aware-ml/pkg/shadow"
+)
+
+// shadowSamples is how many of the latest disagreements /shadow keeps.
+const shadowSamples = 500
+
+// shadowCandidate is the engine configuration decided with in the shadow of
+// the live one.
+type shadowCandidate struct {
+	name string
+	// engine is nil for candidates that run on the live tiers.
+	engine *decision.Engine
+	// policy is nil for candidates that keep each tenant's policy.
+	policy *decision.Policy
+}
+
+// loadShadow reads the candidate from path and builds it on top of engine.
+func loadShadow(path string, engine *decision.Engine) (*shadowCandidate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := shadow.ParseCandidate(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Validate(engine.ConfiguredTierNames()); err != nil {
+		return nil, err
+	}
+	sc := &shadowCandidate{name: c.Name, policy: c.Policy}
+	if c.Tiers != nil {
+		sc.engine = engine.WithTiers(c.Tiers)
+	}
+	return sc, nil
+}
+
+// run replays the request the live engine just decided through the
+// candidate, using the answers the live cascade got, and records where the
+// two disagree.
+func (c *shadowCandidate) run(engine *decision.Engine, req decision.Request, telemetry decision.Telemetry, attempts []decision.Attempt, live shadow.Outcome, start time.Time, samples *shadow.Log) {
+	if c.engine != nil {
+		engine = c.engine
+	}
+	if c.policy != nil {
+		req.Policy = c.policy
+	}
+	if req.Policy != nil && req.Policy.Validate(engine.ConfiguredTierNames()) != nil {
+		// The tenant's policy names tiers the candidate does not have.
+		return
+	}
+	// Give the replay the time that was left when the live engine entered
+	// the cascade, not what is left now.
+	if !req.Deadline.IsZero() {
+		req.Deadline = req.Deadline.Add(time.Since(start))
+	}
+
+	out := shadow.Replay(engine, req, telemetry, attempts)
+	shadowDecisionsTotal.WithLabelValues(req.TenantID).Inc()
+	kind := shadow.Compare(live, out)
+	if kind == "" {
+		return
+	}
+	shadowDisagreementsTotal.WithLabelValues(req.TenantID, kind, out.Reason).Inc()
+	d := shadow.Disagreement{
+		RequestID: req.RequestID,
+		TenantID:  req.TenantID,
+		Candidate: c.name,
+		Kind:      kind,
+		Live:      live,
+		Shadow:    out,
+		At:        time.Now(),
+	}
+	samples.Add(d)
+	if logLevel == "debug" {
+		log.Printf("debug: shadow %s disagrees on %s: %s", c.name, kind, d)
+	}
+}
+
+func shadowHandler(rl *reloader, samples *shadow.Log) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := ""
+		if c := rl.shadow.Load(); c != nil {
+			name = c.name
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"candidate":     name,
+			"disagreements": samples.Recent(r.URL.Query().Get("kind")),
+		})
+	}
+}
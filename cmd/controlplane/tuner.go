@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/cost-aware-ml/pkg/decision"
+	"github.com/cost-aware-ml/pkg/store"
+	"github.com/cost-aware-ml/pkg/tuning"
+)
+
+// runTuner periodically re-tunes the confidence thresholds of every tenant
+// whose policy sets target_accuracy and saves them to a new policy row.
+func runTuner(interval time.Duration, live *decision.LiveEngine, configStore *store.Store) {
+	ticker := time.NewTicker(interval)
+	for range ticker.C {
+		ctx := context.Background()
+		engine := live.Load()
+		policies, err := configStore.ListPolicies(ctx)
+		if err != nil {
+			log.Printf("tuner: failed to list policies: %v", err)
+			continue
+		}
+		for tenantID, policy := range policies {
+			if policy.TargetAccuracy == 0 {
+				continue
+			}
+			result, err := tuning.Run(ctx, configStore, engine, policy, tuning.Options{
+				TenantID:          tenantID,
+				Target:            policy.TargetAccuracy,
+				Since:             time.Now().Add(-calibrationWindow),
+				CalibrationMethod: calibrationMethod,
+			})
+			if err != nil {
+				log.Printf("tuner: failed to tune tenant %s: %v", tenantID, err)
+				continue
+			}
+			if result.Best == nil {
+				log.Printf("tuner: no thresholds reach %.3f for tenant %s over %d traces", policy.TargetAccuracy, tenantID, result.Traces)
+				continue
+			}
+			changed, err := tuning.Apply(ctx, configStore, tuning.ApplyPolicy, tenantID, policy, result.Best.Thresholds)
+			if err != nil {
+				log.Printf("tuner: failed to apply thresholds for tenant %s: %v", tenantID, err)
+				continue
+			}
+			if !changed {
+				continue
+			}
+			log.Printf("tuner: tenant %s thresholds %v (accuracy %.3f, cost %.3f cents)", tenantID, result.Best.Thresholds, result.Best.Accuracy, result.Best.CostCents)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cost-aware-ml/pkg/decision"
+	"github.com/cost-aware-ml/pkg/experiment"
+	"github.com/cost-aware-ml/pkg/store"
+	_ "github.com/lib/pq"
+)
+
+func main() {
+	name := flag.String("name", "", "experiment to define or analyze")
+	define := flag.String("define", "", "experiment definition JSON to save under -name instead of analyzing")
+	disable := flag.Bool("disable", false, "with -define, save the experiment disabled")
+	window := flag.Duration("since", 14*24*time.Hour, "how far back to read enrolled requests and feedback")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	flag.Parse()
+
+	if *name == "" {
+		log.Fatal("-name is required")
+	}
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		log.Fatal("DATABASE_URL is required")
+	}
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		log.Fatalf("failed to connect to db: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	s := store.New(db)
+
+	if *define != "" {
+		data, err := os.ReadFile(*define)
+		if err != nil {
+			log.Fatalf("failed to read definition: %v", err)
+		}
+		e, err := experiment.Parse(*name, data)
+		if err != nil {
+			log.Fatal(err)
+		}
+		tiers, err := s.LoadTiers(ctx)
+		if err != nil {
+			log.Fatalf("failed to load tiers: %v", err)
+		}
+		if err := e.Validate(decision.NewEngineWithTiers(tiers).ConfiguredTierNames()); err != nil {
+			log.Fatal(err)
+		}
+		if err := s.SaveExperiment(ctx, *name, data, !*disable); err != nil {
+			log.Fatalf("failed to save experiment: %v", err)
+		}
+		fmt.Printf("saved experiment %s with %d arms\n", *name, len(e.Arms))
+		return
+	}
+
+	report, err := experiment.Run(ctx, s, *name, time.Now().Add(-*window))
+	if err != nil {
+		log.Fatalf("analysis failed: %v", err)
+	}
+	if *asJSON {
+		json.NewEncoder(os.Stdout).Encode(report)
+		return
+	}
+	printReport(report)
+}
+
+// minUnits is the number of units per arm below which the normal
+// approximation behind the intervals is not to be trusted.
+const minUnits = 30
+
+func printReport(r experiment.Report) {
+	fmt.Printf("experiment %s (95%% confidence intervals; deltas against %s)\n", r.Experiment, baseline(r))
+	fmt.Printf("requests are split by %s, so intervals treat each %s's requests as one cluster;\n", r.Unit, r.Unit)
+	fmt.Printf("they only hold with many %ss per arm (arms with fewer than %d are marked !)\n\n", r.Unit, minUnits)
+	fmt.Printf("%-15s %-9s %-7s %-26s %-26s %-9s %-8s %s\n", "arm", "requests", "units", "cost_cents", "latency_ms", "p95_ms", "labeled", "accuracy")
+	for _, a := range r.Arms {
+		units := fmt.Sprint(a.Units)
+		if a.Units < minUnits {
+			units += " !"
+		}
+		fmt.Printf("%-15s %-9d %-7s %-26s %-26s %-9.0f %-8d %s\n", a.Arm, a.Requests, units,
+			interval(a.CostCents, "%.3f"), interval(a.LatencyMS, "%.1f"), a.P95LatencyMS, a.Labeled, interval(a.Accuracy, "%.3f"))
+	}
+
+	fmt.Println()
+	for _, a := range r.Arms {
+		if a.CostDelta == nil && a.AccuracyDelta == nil {
+			continue
+		}
+		fmt.Printf("%-15s cost %s  latency %s  accuracy %s\n", a.Arm,
+			delta(a.CostDelta, "%+.3f"), delta(a.LatencyDelta, "%+.1f"), delta(a.AccuracyDelta, "%+.3f"))
+	}
+
+	fmt.Println()
+	for _, a := range r.Arms {
+		tiers := make([]string, 0, len(a.TierMix))
+		for t := range a.TierMix {
+			tiers = append(tiers, string(t))
+		}
+		sort.Strings(tiers)
+		mix := make([]string, len(tiers))
+		for i, t := range tiers {
+			mix[i] = fmt.Sprintf("%s=%.1f%%", t, 100*a.TierMix[decision.Tier(t)])
+		}
+		fmt.Printf("%-15s tier mix: %s\n", a.Arm, strings.Join(mix, " "))
+	}
+}
+
+func baseline(r experiment.Report) string {
+	if len(r.Arms) == 0 {
+		return "-"
+	}
+	return r.Arms[0].Arm
+}
+
+func interval(i experiment.Interval, format string) string {
+	return fmt.Sprintf(format+" ["+format+", "+format+"]", i.Mean, i.Low, i.High)
+}
+
+// delta marks differences whose interval excludes zero.
+func delta(i *experiment.Interval, format string) string {
+	if i == nil {
+		return "n/a"
+	}
+	s := interval(*i, format)
+	if i.Low > 0 || i.High < 0 {
+		s += " *"
+	}
+	return s
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cost-aware-ml/pkg/store"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	accuracyWindow  = 24 * time.Hour
+	accuracyRefresh = time.Minute
+)
+
+var (
+	feedbackTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_feedback_total",
+			Help: "Total feedback received",
+		},
+		[]string{"tier", "correct"},
+	)
+	tierAccuracy = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "gateway_tier_accuracy",
+			Help: "Fraction of labeled answers that were correct over the last 24h, by tier",
+		},
+		[]string{"tier"},
+	)
+	tenantAccuracy = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "gateway_tenant_accuracy",
+			Help: "Fraction of labeled answers that were correct over the last 24h, by tenant",
+		},
+		[]string{"tenant"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(feedbackTotal)
+	prometheus.MustRegister(tierAccuracy)
+	prometheus.MustRegister(tenantAccuracy)
+}
+
+type feedbackRequest struct {
+	RequestID string `json:"request_id"`
+	TenantID  string `json:"tenant_id"`
+	Label     string `json:"label"`
+	Correct   *bool  `json:"correct"`
+}
+
+// feedbackRecorder is the part of the store the feedback handler uses.
+type feedbackRecorder interface {
+	RecordFeedback(ctx context.Context, fb store.Feedback) (store.LabeledOutcome, error)
+}
+
+func feedbackHandler(s feedbackRecorder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req feedbackRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		if req.RequestID == "" || (req.Label == "" && req.Correct == nil) {
+			http.Error(w, "request_id and one of label or correct are required", http.StatusBadRequest)
+			return
+		}
+
+		outcome, err := s.RecordFeedback(r.Context(), store.Feedback{
+			RequestID: req.RequestID,
+			TenantID:  req.TenantID,
+			Label:     req.Label,
+			Correct:   req.Correct,
+		})
+		if err == store.ErrNotFound {
+			http.Error(w, "unknown request_id", http.StatusNotFound)
+			return
+		}
+		if err == store.ErrTenantMismatch {
+			http.Error(w, "request_id belongs to another tenant", http.StatusForbidden)
+			return
+		}
+		if err != nil {
+			log.Printf("failed to record feedback for %s: %v", req.RequestID, err)
+			http.Error(w, "failed to record feedback", http.StatusInternalServerError)
+			return
+		}
+
+		feedbackTotal.WithLabelValues(outcome.Tier, strconv.FormatBool(outcome.Correct)).Inc()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"request_id": outcome.RequestID,
+			"tenant_id":  outcome.TenantID,
+			"tier":       outcome.Tier,
+			"correct":    outcome.Correct,
+		})
+	}
+}
+
+type accuracySummary struct {
+	Labeled  int     `json:"labeled"`
+	Correct  int     `json:"correct"`
+	Accuracy float64 `json:"accuracy"`
+}
+
+func (a *accuracySummary) add(st store.AccuracyStat) {
+	a.Labeled += st.Labeled
+	a.Correct += st.Correct
+	a.Accuracy = float64(a.Correct) / float64(a.Labeled)
+}
+
+type accuracyReport struct {
+	Window      string                                 `json:"window"`
+	Tiers       map[string]*accuracySummary            `json:"tiers"`
+	Tenants     map[string]*accuracySummary            `json:"tenants"`
+	TenantTiers map[string]map[string]*accuracySummary `json:"tenant_tiers"`
+}
+
+func summarizeAccuracy(stats []store.AccuracyStat, window time.Duration) accuracyReport {
+	report := accuracyReport{
+		Window:      window.String(),
+		Tiers:       make(map[string]*accuracySummary),
+		Tenants:     make(map[string]*accuracySummary),
+		TenantTiers: make(map[string]map[string]*accuracySummary),
+	}
+	for _, st := range stats {
+		if st.Labeled == 0 {
+			continue
+		}
+		if report.Tiers[st.Tier] == nil {
+			report.Tiers[st.Tier] = &accuracySummary{}
+		}
+		report.Tiers[st.Tier].add(st)
+		if report.Tenants[st.TenantID] == nil {
+			report.Tenants[st.TenantID] = &accuracySummary{}
+			report.TenantTiers[st.TenantID] = make(map[string]*accuracySummary)
+		}
+		report.Tenants[st.TenantID].add(st)
+		report.TenantTiers[st.TenantID][st.Tier] = &accuracySummary{}
+		report.TenantTiers[st.TenantID][st.Tier].add(st)
+	}
+	return report
+}
+
+func accuracyHandler(s *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		window := accuracyWindow
+		if v := r.URL.Query().Get("window"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				http.Error(w, "invalid window", http.StatusBadRequest)
+				return
+			}
+			window = d
+		}
+
+		stats, err := s.AccuracyStats(r.Context(), time.Now().Add(-window))
+		if err != nil {
+			log.Printf("failed to load accuracy stats: %v", err)
+			http.Error(w, "failed to load accuracy", http.StatusInternalServerError)
+			return
+		}
+
+		if tenantID := r.URL.Query().Get("tenant_id"); tenantID != "" {
+			filtered := stats[:0]
+			for _, st := range stats {
+				if st.TenantID == tenantID {
+					filtered = append(filtered, st)
+				}
+			}
+			stats = filtered
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(summarizeAccuracy(stats, window))
+	}
+}
+
+// runAccuracyMetrics keeps the accuracy gauges in line with the last
+// accuracyWindow of feedback.
+func runAccuracyMetrics(s *store.Store) {
+	ticker := time.NewTicker(accuracyRefresh)
+	for {
+		stats, err := s.AccuracyStats(context.Background(), time.Now().Add(-accuracyWindow))
+		if err != nil {
+			log.Printf("failed to refresh accuracy metrics: %v", err)
+		} else {
+			report := summarizeAccuracy(stats, accuracyWindow)
+			tierAccuracy.Reset()
+			for tier, a := range report.Tiers {
+				tierAccuracy.WithLabelValues(tier).Set(a.Accuracy)
+			}
+			tenantAccuracy.Reset()
+			for tenant, a := range report.Tenants {
+				tenantAccuracy.WithLabelValues(tenant).Set(a.Accuracy)
+			}
+		}
+		<-ticker.C
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cost-aware-ml/pkg/store"
+)
+
+type servedRequest struct {
+	tenantID, tier, result string
+}
+
+// fakeFeedback follows the contract of store.RecordFeedback over requests
+// held in memory.
+type fakeFeedback struct {
+	served   map[string]servedRequest
+	recorded map[string]bool
+}
+
+func (f *fakeFeedback) RecordFeedback(ctx context.Context, fb store.Feedback) (store.LabeledOutcome, error) {
+	r, ok := f.served[fb.RequestID]
+	if !ok {
+		return store.LabeledOutcome{}, store.ErrNotFound
+	}
+	if fb.TenantID != "" && fb.TenantID != r.tenantID {
+		return store.LabeledOutcome{}, store.ErrTenantMismatch
+	}
+	o := store.LabeledOutcome{RequestID: fb.RequestID, TenantID: r.tenantID, Tier: r.tier, Correct: fb.Label == r.result}
+	if fb.Correct != nil {
+		o.Correct = *fb.Correct
+	}
+	f.recorded[fb.RequestID] = o.Correct
+	return o, nil
+}
+
+func postFeedback(t *testing.T, h http.HandlerFunc, body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodPost, "/feedback", strings.NewReader(body)))
+	var got map[string]interface{}
+	if w.Code == http.StatusOK {
+		if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+			t.Fatalf("invalid response body: %v", err)
+		}
+	}
+	return w, got
+}
+
+func TestFeedbackHandler(t *testing.T) {
+	s := &fakeFeedback{
+		served:   map[string]servedRequest{"r1": {tenantID: "tenant-1", tier: "tier0", result: "cat"}},
+		recorded: make(map[string]bool),
+	}
+	h := feedbackHandler(s)
+
+	if w, _ := postFeedback(t, h, `{"request_id": "missing", "correct": true}`); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown request_id, got %d", w.Code)
+	}
+
+	w, got := postFeedback(t, h, `{"request_id": "r1", "label": "dog"}`)
+	if w.Code != http.StatusOK || got["correct"] != false {
+		t.Fatalf("expected a wrong label to grade incorrect, got %d %v", w.Code, got)
+	}
+	// A second label for the same request replaces the first.
+	w, got = postFeedback(t, h, `{"request_id": "r1", "label": "cat"}`)
+	if w.Code != http.StatusOK || got["correct"] != true || !s.recorded["r1"] {
+		t.Errorf("expected a duplicate label to regrade the request, got %d %v", w.Code, got)
+	}
+
+	if w, _ := postFeedback(t, h, `{"request_id": "r1", "tenant_id": "tenant-2", "correct": false}`); w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for another tenant's request, got %d", w.Code)
+	}
+	if !s.recorded["r1"] {
+		t.Error("expected feedback from another tenant not to be recorded")
+	}
+	if w, _ := postFeedback(t, h, `{"request_id": "r1", "tenant_id": "tenant-1", "correct": true}`); w.Code != http.StatusOK {
+		t.Errorf("expected the owning tenant's feedback to be accepted, got %d", w.Code)
+	}
+
+	if w, _ := postFeedback(t, h, `{"request_id": "r1"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without label or correct, got %d", w.Code)
+	}
+}
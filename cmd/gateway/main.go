@@ -5,18 +5,24 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/cost-aware-ml/pkg/cache"
+	"github.com/cost-aware-ml/pkg/deadline"
+	"github.com/cost-aware-ml/pkg/decision"
 	"github.com/cost-aware-ml/pkg/observability"
 	"github.com/cost-aware-ml/pkg/ratelimit"
 	"github.com/cost-aware-ml/pkg/retry"
+	"github.com/cost-aware-ml/pkg/store"
 	_ "github.com/lib/pq"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -80,9 +86,6 @@ func init() {
 }
 
 var controlplaneURL = os.Getenv("CONTROLPLANE_URL")
-var tier0URL = os.Getenv("TIER0_URL")
-var tier1URL = os.Getenv("TIER1_URL")
-var tier2URL = os.Getenv("TIER2_URL")
 var dbURL = os.Getenv("DATABASE_URL")
 var redisURL = os.Getenv("REDIS_URL")
 
@@ -102,6 +105,58 @@ type QueuedRequest struct {
 	done    chan bool
 	body    []byte
 	request map[string]interface{}
+	state   atomic.Int32
+}
+
+const (
+	requestQueued int32 = iota
+	requestRunning
+	requestAbandoned
+)
+
+// start claims a dequeued request for handling; it fails if the caller has
+// already given up on it.
+func (q *QueuedRequest) start() bool {
+	return q.state.CompareAndSwap(requestQueued, requestRunning)
+}
+
+// abandon gives up on a request still waiting in the queue; it fails once
+// the request is being handled.
+func (q *QueuedRequest) abandon() bool {
+	return q.state.CompareAndSwap(requestQueued, requestAbandoned)
+}
+
+// awaitQueued waits for q to be answered. A request with a deadline waits
+// as long as the deadline allows; one without waits in the queue for at
+// most timeout. Once a worker has picked the request up, it answers.
+func awaitQueued(ctx context.Context, w http.ResponseWriter, q *QueuedRequest, timeout time.Duration) {
+	var queueWait <-chan time.Time
+	if _, ok := ctx.Deadline(); !ok {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		queueWait = timer.C
+	}
+
+	select {
+	case <-q.done:
+	case <-ctx.Done():
+		if q.abandon() {
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				deadlineExceeded(w)
+			}
+			return
+		}
+		// Already being handled; the handler answers as soon as it
+		// notices the deadline.
+		<-q.done
+	case <-queueWait:
+		if q.abandon() {
+			requestsTotal.WithLabelValues("queue_timeout").Inc()
+			http.Error(w, "request timeout", http.StatusRequestTimeout)
+			return
+		}
+		<-q.done
+	}
 }
 
 func NewRequestQueue(size int) *RequestQueue {
@@ -130,15 +185,6 @@ func main() {
 	if controlplaneURL == "" {
 		controlplaneURL = "http://controlplane:8081"
 	}
-	if tier0URL == "" {
-		tier0URL = "http://tier0-fast:8090"
-	}
-	if tier1URL == "" {
-		tier1URL = "http://tier1-mid:8091"
-	}
-	if tier2URL == "" {
-		tier2URL = "http://tier2-best:8092"
-	}
 
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -180,16 +226,19 @@ func main() {
 	shutdown := observability.Init("gateway")
 	defer shutdown()
 
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
+	// Calls to the controlplane are bounded by each request's deadline,
+	// not a client-wide timeout that would cut long SLOs short.
+	client := &http.Client{}
 
 	requestQueue := NewRequestQueue(maxQueueSize)
 
 	go func() {
 		for {
 			queuedReq := requestQueue.Dequeue()
-			handleInference(queuedReq.req.Context(), queuedReq.resp, queuedReq.body, queuedReq.request, client, db, rateLimiter, responseCache, controlplaneURL, tier0URL, tier1URL, tier2URL)
+			if !queuedReq.start() {
+				continue
+			}
+			handleInference(queuedReq.req.Context(), queuedReq.resp, queuedReq.body, queuedReq.request, client, db, rateLimiter, responseCache, controlplaneURL)
 			close(queuedReq.done)
 		}
 	}()
@@ -201,6 +250,13 @@ func main() {
 
 	http.Handle("/metrics", promhttp.Handler())
 
+	if db != nil {
+		feedbackStore := store.New(db)
+		http.HandleFunc("/feedback", feedbackHandler(feedbackStore))
+		http.HandleFunc("/accuracy", accuracyHandler(feedbackStore))
+		go runAccuracyMetrics(feedbackStore)
+	}
+
 	http.HandleFunc("/infer", func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 		ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(r.Header))
@@ -222,6 +278,21 @@ func main() {
 			tenantID = "default"
 		}
 
+		if r.URL.Query().Get("explain") == "true" || r.Header.Get("X-Explain") == "true" {
+			req["explain"] = true
+		}
+
+		// The request's latency budget becomes its deadline here, unless an
+		// upstream caller already sent one.
+		var cancel context.CancelFunc
+		if r.Header.Get(deadline.Header) != "" {
+			ctx, cancel = deadline.FromHeader(ctx, r.Header)
+		} else {
+			maxLatencyMS, _ := req["max_latency_ms"].(float64)
+			ctx, cancel = deadline.WithBudget(ctx, int(maxLatencyMS))
+		}
+		defer cancel()
+
 		if rateLimiter != nil {
 			allowed, err := rateLimiter.TokenBucket(ctx, "ratelimit:"+tenantID, 100, 10.0)
 			if err != nil {
@@ -248,19 +319,33 @@ func main() {
 			return
 		}
 
-		select {
-		case <-queuedReq.done:
-		case <-time.After(queueTimeout):
-			requestsTotal.WithLabelValues("queue_timeout").Inc()
-			http.Error(w, "request timeout", http.StatusRequestTimeout)
-		}
+		awaitQueued(ctx, w, queuedReq, queueTimeout)
 	})
 
 	log.Printf("gateway listening on :%s", port)
 	log.Fatal(http.ListenAndServe(":"+port, nil))
 }
 
-func handleInference(ctx context.Context, w http.ResponseWriter, body []byte, req map[string]interface{}, client *http.Client, db *sql.DB, rateLimiter *ratelimit.RateLimiter, responseCache *cache.Cache, controlplaneURL, tier0URL, tier1URL, tier2URL string) {
+// rejectedError carries a 4xx answer from the controlplane (invalid policy,
+// exhausted budget) or its 504 for a missed deadline back to the client
+// unchanged.
+type rejectedError struct {
+	status  int
+	message string
+}
+
+func (e *rejectedError) Error() string {
+	return fmt.Sprintf("controlplane rejected request (%d): %s", e.status, e.message)
+}
+
+// deadlineExceeded answers 504 once the request's latency budget has run
+// out.
+func deadlineExceeded(w http.ResponseWriter) {
+	requestsTotal.WithLabelValues("deadline_exceeded").Inc()
+	http.Error(w, "deadline exceeded: max_latency_ms elapsed before an answer was ready", http.StatusGatewayTimeout)
+}
+
+func handleInference(ctx context.Context, w http.ResponseWriter, body []byte, req map[string]interface{}, client *http.Client, db *sql.DB, rateLimiter *ratelimit.RateLimiter, responseCache *cache.Cache, controlplaneURL string) {
 	start := time.Now()
 
 	requestID, _ := req["request_id"].(string)
@@ -299,18 +384,23 @@ func handleInference(ctx context.Context, w http.ResponseWriter, body []byte, re
 		}
 	}
 
+	req["request_id"] = requestID
 	decideReq, _ := json.Marshal(req)
-	
-	var decision map[string]interface{}
+
+	var decided map[string]interface{}
 	var err error
 
 	retryConfig := retry.DefaultConfig()
 	err = retry.Retry(retryConfig, func() error {
+		if ctx.Err() != nil {
+			return retry.Permanent(ctx.Err())
+		}
 		httpReq, callErr := http.NewRequestWithContext(ctx, "POST", controlplaneURL+"/decide", bytes.NewBuffer(decideReq))
 		if callErr != nil {
 			return callErr
 		}
 		otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(httpReq.Header))
+		deadline.Inject(ctx, httpReq.Header)
 
 		resp, callErr := client.Do(httpReq)
 		if callErr != nil {
@@ -318,68 +408,54 @@ func handleInference(ctx context.Context, w http.ResponseWriter, body []byte, re
 		}
 		defer resp.Body.Close()
 
+		if resp.StatusCode == http.StatusGatewayTimeout || (resp.StatusCode >= 400 && resp.StatusCode < 500) {
+			msg, _ := io.ReadAll(resp.Body)
+			return retry.Permanent(&rejectedError{status: resp.StatusCode, message: strings.TrimSpace(string(msg))})
+		}
 		if resp.StatusCode != http.StatusOK {
 			return fmt.Errorf("controlplane returned %d", resp.StatusCode)
 		}
 
-		callErr = json.NewDecoder(resp.Body).Decode(&decision)
+		callErr = json.NewDecoder(resp.Body).Decode(&decided)
 		return callErr
 	})
 
-	if err != nil {
-		requestsTotal.WithLabelValues("controlplane_error").Inc()
-		http.Error(w, "controlplane error", http.StatusInternalServerError)
+	if rejected, ok := err.(*rejectedError); ok && rejected.status == http.StatusGatewayTimeout {
+		requestsTotal.WithLabelValues("deadline_exceeded").Inc()
+		http.Error(w, rejected.message, rejected.status)
 		return
 	}
-
-	tier, _ := decision["tier"].(string)
-	var workerURL string
-	switch tier {
-	case "tier0":
-		workerURL = tier0URL
-	case "tier1":
-		workerURL = tier1URL
-	case "tier2":
-		workerURL = tier2URL
-	default:
-		requestsTotal.WithLabelValues("unknown_tier").Inc()
-		http.Error(w, "unknown tier", http.StatusInternalServerError)
+	if errors.Is(err, context.DeadlineExceeded) {
+		deadlineExceeded(w)
+		return
+	}
+	if rejected, ok := err.(*rejectedError); ok {
+		requestsTotal.WithLabelValues("rejected").Inc()
+		http.Error(w, rejected.message, rejected.status)
 		return
 	}
-
-	var result map[string]interface{}
-	
-	err = retry.Retry(retryConfig, func() error {
-		httpReq, callErr := http.NewRequestWithContext(ctx, "POST", workerURL+"/infer", bytes.NewBuffer(body))
-		if callErr != nil {
-			return callErr
-		}
-		otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(httpReq.Header))
-
-		workerResp, callErr := client.Do(httpReq)
-		if callErr != nil {
-			return callErr
-		}
-		defer workerResp.Body.Close()
-
-		if workerResp.StatusCode != http.StatusOK {
-			return fmt.Errorf("worker returned %d", workerResp.StatusCode)
-		}
-
-		callErr = json.NewDecoder(workerResp.Body).Decode(&result)
-		return callErr
-	})
-
 	if err != nil {
-		requestsTotal.WithLabelValues("worker_error").Inc()
-		http.Error(w, "worker error", http.StatusInternalServerError)
+		requestsTotal.WithLabelValues("controlplane_error").Inc()
+		http.Error(w, "controlplane error", http.StatusInternalServerError)
 		return
 	}
 
+	// The controlplane has already run the cascade, so its answer carries the
+	// worker result; calling the worker again would bill the tier twice.
+	result := decided
+	tier, _ := result["tier"].(string)
+
 	if responseCache != nil && !cacheHit {
 		cacheKey, err := responseCache.Key(tenantID, req["input"])
 		if err == nil {
-			resultJSON, _ := json.Marshal(result)
+			// Explanations describe this decision only; a cache hit makes none.
+			cached := make(map[string]interface{}, len(result))
+			for k, v := range result {
+				if k != "explanation" {
+					cached[k] = v
+				}
+			}
+			resultJSON, _ := json.Marshal(cached)
 			responseCache.Set(ctx, cacheKey, resultJSON)
 		}
 		cacheMisses.WithLabelValues(tier).Inc()
@@ -393,8 +469,20 @@ func handleInference(ctx context.Context, w http.ResponseWriter, body []byte, re
 		confidence, _ := result["confidence"].(float64)
 		latency, _ := result["model_latency_ms"].(float64)
 		budget, _ := req["budget"].(float64)
-		db.Exec("INSERT INTO inference_requests (request_id, tier, budget, confidence, latency_ms) VALUES ($1, $2, $3, $4, $5)",
-			requestID, tier, budget, confidence, int(latency))
+		reason, _ := result["reason"].(string)
+		cost, _ := result["estimated_cost_cents"].(float64)
+		answer, _ := result["result"].(string)
+		breakdown, _ := json.Marshal(result["cost_breakdown"])
+		strategy, _ := result["strategy"].(string)
+		propensity, _ := result["propensity"].(float64)
+		banditContext, _ := result["context"].(string)
+		experiment, _ := result["experiment"].(string)
+		arm, _ := result["arm"].(string)
+		unit, _ := result["experiment_unit"].(string)
+		entryTier, _ := result["entry_tier"].(string)
+		input := decision.Features(req["input"])
+		db.Exec("INSERT INTO inference_requests (request_id, tenant_id, tier, reason, budget, confidence, latency_ms, cost_cents, cost_breakdown, result, strategy, propensity, bandit_context, input_tokens, input_bytes, input_items, experiment, arm, experiment_unit, entry_tier) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NULLIF($13, ''), $14, $15, $16, NULLIF($17, ''), NULLIF($18, ''), NULLIF($19, ''), NULLIF($20, ''))",
+			requestID, tenantID, tier, reason, budget, confidence, int(latency), cost, breakdown, answer, strategy, propensity, banditContext, input.Tokens, input.Bytes, input.Items, experiment, arm, unit, entryTier)
 	}
 
 	traceID := trace.SpanFromContext(ctx).SpanContext().TraceID().String()
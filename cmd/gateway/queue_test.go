@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// serveAfter picks q up after pickup and answers 200 after another 30ms.
+func serveAfter(q *QueuedRequest, pickup time.Duration) {
+	go func() {
+		time.Sleep(pickup)
+		if !q.start() {
+			return
+		}
+		time.Sleep(30 * time.Millisecond)
+		q.resp.WriteHeader(http.StatusOK)
+		close(q.done)
+	}()
+}
+
+func TestAwaitQueued(t *testing.T) {
+	tests := []struct {
+		name     string
+		deadline time.Duration
+		// pickup is when a worker takes the request; zero never.
+		pickup time.Duration
+		status int
+	}{
+		{"queued past the timeout", 0, 0, http.StatusRequestTimeout},
+		{"picked up before the timeout", 0, 10 * time.Millisecond, http.StatusOK},
+		{"deadline outlasts the timeout", 500 * time.Millisecond, 40 * time.Millisecond, http.StatusOK},
+		{"deadline passes in the queue", 50 * time.Millisecond, 0, http.StatusGatewayTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.deadline > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.deadline)
+				defer cancel()
+			}
+			w := httptest.NewRecorder()
+			q := &QueuedRequest{resp: w, done: make(chan bool)}
+			if tt.pickup > 0 {
+				serveAfter(q, tt.pickup)
+			}
+			awaitQueued(ctx, w, q, 20*time.Millisecond)
+			if w.Code != tt.status {
+				t.Errorf("expected %d, got %d", tt.status, w.Code)
+			}
+		})
+	}
+}
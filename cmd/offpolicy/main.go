@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cost-aware-ml/pkg/decision"
+	"github.com/cost-aware-ml/pkg/offpolicy"
+	"github.com/cost-aware-ml/pkg/store"
+	_ "github.com/lib/pq"
+)
+
+func main() {
+	tenantID := flag.String("tenant", "", "tenant whose traffic to replay (empty replays all traffic)")
+	window := flag.Duration("since", 7*24*time.Hour, "how far back to read decisions and feedback")
+	policyFile := flag.String("policy", "", "candidate policy JSON applied to every tenant (defaults to each tenant's current policy)")
+	thresholds := flag.String("thresholds", "", "candidate default thresholds, e.g. tier0=0.8,tier1=0.9")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	flag.Parse()
+
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		log.Fatal("DATABASE_URL is required")
+	}
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		log.Fatalf("failed to connect to db: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	s := store.New(db)
+
+	tiers, err := s.LoadTiers(ctx)
+	if err != nil {
+		log.Fatalf("failed to load tiers: %v", err)
+	}
+	if err := overrideThresholds(tiers, *thresholds); err != nil {
+		log.Fatalf("invalid -thresholds: %v", err)
+	}
+	engine := decision.NewEngineWithTiers(tiers)
+
+	var policy *decision.Policy
+	if *policyFile != "" {
+		data, err := os.ReadFile(*policyFile)
+		if err != nil {
+			log.Fatalf("failed to read policy: %v", err)
+		}
+		policy, err = decision.ParsePolicy(data)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := policy.Validate(engine.ConfiguredTierNames()); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	report, err := offpolicy.Run(ctx, s, engine, offpolicy.Options{
+		TenantID: *tenantID,
+		Since:    time.Now().Add(-*window),
+		Policy:   policy,
+	})
+	if err != nil {
+		log.Fatalf("evaluation failed: %v", err)
+	}
+
+	if *asJSON {
+		json.NewEncoder(os.Stdout).Encode(report)
+		return
+	}
+	printReport(report, engine.TierNames())
+}
+
+func overrideThresholds(tiers []decision.TierConfig, spec string) error {
+	if spec == "" {
+		return nil
+	}
+	for _, pair := range strings.Split(spec, ",") {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("expected tier=threshold, got %q", pair)
+		}
+		threshold, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		found := false
+		for i := range tiers {
+			if string(tiers[i].Name) == name {
+				tiers[i].DefaultConfThreshold = threshold
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown tier %q", name)
+		}
+	}
+	return nil
+}
+
+func printReport(r offpolicy.Report, tiers []decision.Tier) {
+	fmt.Printf("replayed %d requests (%d labeled), candidate takes the logged entry and tier on %d (max weight %.1f)\n\n",
+		r.Traces, r.Labeled, r.Matched, r.MaxWeight)
+	fmt.Printf("%-15s %-12s %-10s %s\n", "estimator", "cost", "accuracy", "latency_ms")
+	rows := []struct {
+		name string
+		o    offpolicy.Outcome
+	}{
+		{"logged", r.Logged},
+		{"ips", r.IPS},
+		{"snips", r.SNIPS},
+		{"doubly_robust", r.DR},
+		{"direct", r.Direct},
+	}
+	for _, row := range rows {
+		fmt.Printf("%-15s %-12.3f %-10.3f %.1f\n", row.name, row.o.CostCents, row.o.Accuracy, row.o.LatencyMS)
+	}
+
+	fmt.Println()
+	mix := make([]string, 0, len(r.TierMix))
+	for _, t := range tiers {
+		if share, ok := r.TierMix[t]; ok {
+			mix = append(mix, fmt.Sprintf("%s=%.1f%%", t, 100*share))
+		}
+	}
+	fmt.Printf("candidate tier mix: %s\n", strings.Join(mix, " "))
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/cost-aware-ml/pkg/decision"
+	"github.com/cost-aware-ml/pkg/simulation"
+)
+
+type configFlags []string
+
+func (c *configFlags) String() string { return strings.Join(*c, ",") }
+
+func (c *configFlags) Set(v string) error {
+	*c = append(*c, v)
+	return nil
+}
+
+func main() {
+	var configs configFlags
+	flag.Var(&configs, "config", "engine configuration JSON ({name, tiers, policy}); repeat to compare")
+	tracePath := flag.String("trace", "", "JSON-lines request trace (- reads stdin); required")
+	profilesPath := flag.String("profiles", "", "synthetic worker profiles JSON keyed by tier (defaults mirror services/workers)")
+	recorded := flag.Bool("recorded", true, "replay outcomes recorded in the trace where present")
+	seed := flag.Int64("seed", 1, "random seed for the synthetic workers")
+	asJSON := flag.Bool("json", false, "print results as JSON")
+	flag.Parse()
+
+	if *tracePath == "" {
+		log.Fatal("-trace is required")
+	}
+
+	trace, err := simulation.ReadTraceFile(*tracePath)
+	if err != nil {
+		log.Fatalf("failed to read trace: %v", err)
+	}
+	profiles, err := simulation.LoadProfiles(*profilesPath)
+	if err != nil {
+		log.Fatalf("failed to load profiles: %v", err)
+	}
+
+	runs := []simulation.Config{{Name: "default"}}
+	if len(configs) > 0 {
+		runs = runs[:0]
+		for _, path := range configs {
+			c, err := simulation.LoadConfig(path)
+			if err != nil {
+				log.Fatalf("failed to load config: %v", err)
+			}
+			runs = append(runs, c)
+		}
+	}
+
+	results := make([]simulation.Result, 0, len(runs))
+	for _, c := range runs {
+		// Every configuration sees the same worker draws.
+		var worker simulation.Worker = simulation.NewSynthetic(profiles, *seed)
+		if *recorded {
+			worker = simulation.Recorded{Fallback: worker}
+		}
+		result, err := simulation.Run(c, trace, worker)
+		if err != nil {
+			log.Fatalf("%s: %v", c.Name, err)
+		}
+		results = append(results, result)
+	}
+
+	if *asJSON {
+		json.NewEncoder(os.Stdout).Encode(results)
+		return
+	}
+	printResults(results)
+}
+
+func printResults(results []simulation.Result) {
+	fmt.Printf("%-20s %-9s %-7s %-11s %-9s %-11s %-8s %-8s %s\n",
+		"config", "requests", "failed", "mean_cost", "accuracy", "escalations", "slo_viol", "p99_ms", "tier mix")
+	for _, r := range results {
+		fmt.Printf("%-20s %-9d %-7d %-11.3f %-9.3f %-11d %-8d %-8d %s\n",
+			r.Name, r.Requests, r.Failed, r.MeanCostCents, r.Accuracy, r.Escalations, r.SLOViolations, r.P99LatencyMS, formatMix(r.TierMix))
+	}
+}
+
+func formatMix(mix map[decision.Tier]float64) string {
+	tiers := make([]string, 0, len(mix))
+	for tier := range mix {
+		tiers = append(tiers, string(tier))
+	}
+	sort.Strings(tiers)
+	parts := make([]string, len(tiers))
+	for i, tier := range tiers {
+		parts[i] = fmt.Sprintf("%s=%.0f%%", tier, 100*mix[decision.Tier(tier)])
+	}
+	return strings.Join(parts, " ")
+}
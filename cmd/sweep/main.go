@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cost-aware-ml/pkg/decision"
+	"github.com/cost-aware-ml/pkg/simulation"
+	"github.com/cost-aware-ml/pkg/tuning"
+)
+
+func main() {
+	tracePath := flag.String("trace", "", "JSON-lines request trace (- reads stdin); required")
+	configPath := flag.String("config", "", "base engine configuration JSON ({name, tiers, policy})")
+	profilesPath := flag.String("profiles", "", "synthetic worker profiles JSON keyed by tier (defaults mirror services/workers)")
+	recorded := flag.Bool("recorded", true, "replay outcomes recorded in the trace where present")
+	seed := flag.Int64("seed", 1, "random seed for the synthetic workers")
+	thresholds := flag.String("thresholds", "0.5:0.95:0.05", "confidence thresholds to try per tier, as lo:hi:step or a comma list")
+	budgets := flag.String("budgets", "", "default per-request budgets in cents to try, comma separated")
+	cutoffs := flag.String("error-cutoffs", "", "max error rates to try for every tier, comma separated")
+	format := flag.String("format", "csv", "output format: csv or json")
+	all := flag.Bool("all", false, "print every grid point instead of the Pareto frontier")
+	flag.Parse()
+
+	if *tracePath == "" {
+		log.Fatal("-trace is required")
+	}
+
+	trace, err := simulation.ReadTraceFile(*tracePath)
+	if err != nil {
+		log.Fatalf("failed to read trace: %v", err)
+	}
+	profiles, err := simulation.LoadProfiles(*profilesPath)
+	if err != nil {
+		log.Fatalf("failed to load profiles: %v", err)
+	}
+	base := simulation.Config{Name: "base"}
+	if *configPath != "" {
+		base, err = simulation.LoadConfig(*configPath)
+		if err != nil {
+			log.Fatalf("failed to load config: %v", err)
+		}
+	}
+
+	var grid simulation.Grid
+	if grid.Thresholds, err = parseValues(*thresholds); err != nil {
+		log.Fatalf("invalid -thresholds: %v", err)
+	}
+	if grid.DefaultBudgets, err = parseValues(*budgets); err != nil {
+		log.Fatalf("invalid -budgets: %v", err)
+	}
+	if grid.ErrorRateCutoffs, err = parseValues(*cutoffs); err != nil {
+		log.Fatalf("invalid -error-cutoffs: %v", err)
+	}
+
+	points, err := simulation.Sweep(base, trace, grid, func() simulation.Worker {
+		var worker simulation.Worker = simulation.NewSynthetic(profiles, *seed)
+		if *recorded {
+			worker = simulation.Recorded{Fallback: worker}
+		}
+		return worker
+	})
+	if err != nil {
+		log.Fatalf("sweep failed: %v", err)
+	}
+	log.Printf("simulated %d configurations over %d requests", len(points), len(trace))
+
+	if !*all {
+		points = simulation.ParetoFrontier(points)
+	}
+	sort.SliceStable(points, func(i, j int) bool {
+		return points[i].Result.MeanCostCents < points[j].Result.MeanCostCents
+	})
+
+	switch *format {
+	case "json":
+		json.NewEncoder(os.Stdout).Encode(points)
+	case "csv":
+		if err := writeCSV(points); err != nil {
+			log.Fatalf("failed to write csv: %v", err)
+		}
+	default:
+		log.Fatalf("unknown format %q", *format)
+	}
+}
+
+// parseValues accepts "lo:hi:step" or a comma-separated list.
+func parseValues(spec string) ([]float64, error) {
+	if spec == "" {
+		return nil, nil
+	}
+	if parts := strings.Split(spec, ":"); len(parts) == 3 {
+		var bounds [3]float64
+		for i, p := range parts {
+			v, err := strconv.ParseFloat(p, 64)
+			if err != nil {
+				return nil, err
+			}
+			bounds[i] = v
+		}
+		if bounds[2] <= 0 {
+			return nil, fmt.Errorf("step must be positive")
+		}
+		return tuning.Grid(bounds[0], bounds[1], bounds[2]), nil
+	}
+	var values []float64
+	for _, p := range strings.Split(spec, ",") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func writeCSV(points []simulation.Point) error {
+	var tiers []decision.Tier
+	if len(points) > 0 {
+		for tier := range points[0].Thresholds {
+			tiers = append(tiers, tier)
+		}
+		sort.Slice(tiers, func(i, j int) bool { return tiers[i] < tiers[j] })
+	}
+
+	w := csv.NewWriter(os.Stdout)
+	header := make([]string, 0, len(tiers)+8)
+	for _, tier := range tiers {
+		header = append(header, string(tier)+"_threshold")
+	}
+	header = append(header, "default_budget", "max_error_rate", "mean_cost_cents", "accuracy", "p99_latency_ms", "slo_violations", "failed", "requests")
+	w.Write(header)
+
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', 4, 64) }
+	for _, p := range points {
+		row := make([]string, 0, len(header))
+		for _, tier := range tiers {
+			row = append(row, f(p.Thresholds[tier]))
+		}
+		cutoff := ""
+		if p.MaxErrorRate != nil {
+			cutoff = f(*p.MaxErrorRate)
+		}
+		r := p.Result
+		row = append(row, f(p.DefaultBudget), cutoff, f(r.MeanCostCents), f(r.Accuracy),
+			strconv.Itoa(r.P99LatencyMS), strconv.Itoa(r.SLOViolations), strconv.Itoa(r.Failed), strconv.Itoa(r.Requests))
+		w.Write(row)
+	}
+	w.Flush()
+	return w.Error()
+}
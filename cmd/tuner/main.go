@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/cost-aware-ml/pkg/decision"
+	"github.com/cost-aware-ml/pkg/store"
+	"github.com/cost-aware-ml/pkg/tuning"
+	_ "github.com/lib/pq"
+)
+
+func main() {
+	tenantID := flag.String("tenant", "", "tenant to tune (empty tunes on all traffic)")
+	target := flag.Float64("target", 0, "target accuracy (defaults to the tenant policy's target_accuracy, then 0.9)")
+	window := flag.Duration("since", 7*24*time.Hour, "how far back to read decisions and feedback")
+	step := flag.Float64("step", 0.05, "threshold grid step")
+	method := flag.String("calibration", os.Getenv("CALIBRATION_METHOD"), "calibration method (isotonic or platt)")
+	apply := flag.String("apply", "", "write the chosen thresholds to \"tiers\" or \"policy\"")
+	asJSON := flag.Bool("json", false, "print the result as JSON")
+	flag.Parse()
+
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		log.Fatal("DATABASE_URL is required")
+	}
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		log.Fatalf("failed to connect to db: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	s := store.New(db)
+
+	tiers, err := s.LoadTiers(ctx)
+	if err != nil {
+		log.Fatalf("failed to load tiers: %v", err)
+	}
+	engine := decision.NewEngineWithTiers(tiers)
+
+	var policy *decision.Policy
+	if *tenantID != "" {
+		policy, err = s.LoadPolicy(ctx, *tenantID)
+		if err != nil {
+			log.Fatalf("failed to load policy for tenant %s: %v", *tenantID, err)
+		}
+	}
+	if *target == 0 && policy != nil {
+		*target = policy.TargetAccuracy
+	}
+	if *target == 0 {
+		*target = 0.9
+	}
+
+	result, err := tuning.Run(ctx, s, engine, policy, tuning.Options{
+		TenantID:          *tenantID,
+		Target:            *target,
+		Since:             time.Now().Add(-*window),
+		Step:              *step,
+		CalibrationMethod: *method,
+	})
+	if err != nil {
+		log.Fatalf("tuning failed: %v", err)
+	}
+
+	if *asJSON {
+		json.NewEncoder(os.Stdout).Encode(result)
+	} else {
+		printResult(result, engine.Cascade(policy))
+	}
+
+	if *apply == "" {
+		return
+	}
+	if result.Best == nil {
+		log.Fatalf("no thresholds reach target accuracy %.3f; nothing applied", *target)
+	}
+	changed, err := tuning.Apply(ctx, s, *apply, *tenantID, policy, result.Best.Thresholds)
+	if err != nil {
+		log.Fatalf("failed to apply thresholds: %v", err)
+	}
+	if !changed {
+		log.Printf("%s already has these thresholds; nothing applied", *apply)
+		return
+	}
+	log.Printf("applied thresholds to %s", *apply)
+}
+
+func printResult(result tuning.Result, cascade []decision.TierConfig) {
+	fmt.Printf("replayed %d traces, target accuracy %.3f\n\n", result.Traces, result.Target)
+	fmt.Printf("%-10s %-10s %s\n", "cost", "accuracy", "thresholds")
+	for _, c := range result.Frontier {
+		fmt.Printf("%-10.3f %-10.3f %s\n", c.CostCents, c.Accuracy, formatThresholds(c.Thresholds, cascade))
+	}
+	fmt.Println()
+	if result.Best == nil {
+		fmt.Println("no thresholds reach the target accuracy")
+		return
+	}
+	fmt.Printf("cheapest thresholds meeting target: %s (cost %.3f cents, accuracy %.3f)\n",
+		formatThresholds(result.Best.Thresholds, cascade), result.Best.CostCents, result.Best.Accuracy)
+}
+
+func formatThresholds(thresholds map[decision.Tier]float64, cascade []decision.TierConfig) string {
+	names := make([]string, 0, len(thresholds))
+	order := make(map[decision.Tier]int)
+	for i, t := range cascade {
+		order[t.Name] = i
+	}
+	tiers := make([]decision.Tier, 0, len(thresholds))
+	for tier := range thresholds {
+		tiers = append(tiers, tier)
+	}
+	sort.Slice(tiers, func(i, j int) bool { return order[tiers[i]] < order[tiers[j]] })
+	for _, tier := range tiers {
+		names = append(names, fmt.Sprintf("%s=%.2f", tier, thresholds[tier]))
+	}
+	return fmt.Sprint(names)
+}
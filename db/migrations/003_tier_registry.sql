@@ -0,0 +1,6 @@
+ALTER TABLE tiers ADD COLUMN IF NOT EXISTS url VARCHAR(255);
+ALTER TABLE tiers ADD COLUMN IF NOT EXISTS max_error_rate FLOAT;
+
+UPDATE tiers SET url = 'http://tier0-fast:8090' WHERE name = 'tier0' AND url IS NULL;
+UPDATE tiers SET url = 'http://tier1-mid:8091', max_error_rate = 0.1 WHERE name = 'tier1' AND url IS NULL;
+UPDATE tiers SET url = 'http://tier2-best:8092', max_error_rate = 0.15 WHERE name = 'tier2' AND url IS NULL;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS tenant_spend (
+    tenant_id VARCHAR(255) NOT NULL,
+    billing_month DATE NOT NULL,
+    spent_cents FLOAT NOT NULL DEFAULT 0,
+    request_count INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, billing_month)
+);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS budget_reservations (
+    request_id VARCHAR(255) PRIMARY KEY,
+    tenant_id VARCHAR(255) NOT NULL,
+    billing_month DATE NOT NULL,
+    amount_cents FLOAT NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX idx_budget_reservations_tenant ON budget_reservations(tenant_id, billing_month);
+CREATE INDEX idx_budget_reservations_expires_at ON budget_reservations(expires_at);
@@ -0,0 +1,8 @@
+ALTER TABLE tiers ADD COLUMN IF NOT EXISTS bill_failed_attempts BOOLEAN DEFAULT false;
+
+ALTER TABLE inference_requests ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(255);
+ALTER TABLE inference_requests ADD COLUMN IF NOT EXISTS reason VARCHAR(100);
+ALTER TABLE inference_requests ADD COLUMN IF NOT EXISTS cost_cents FLOAT;
+ALTER TABLE inference_requests ADD COLUMN IF NOT EXISTS cost_breakdown JSONB;
+
+CREATE INDEX IF NOT EXISTS idx_inference_requests_tenant ON inference_requests(tenant_id, created_at);
@@ -0,0 +1,5 @@
+ALTER TABLE tiers ADD COLUMN IF NOT EXISTS expected_accuracy FLOAT;
+
+UPDATE tiers SET expected_accuracy = 0.72 WHERE name = 'tier0' AND expected_accuracy IS NULL;
+UPDATE tiers SET expected_accuracy = 0.88 WHERE name = 'tier1' AND expected_accuracy IS NULL;
+UPDATE tiers SET expected_accuracy = 0.96 WHERE name = 'tier2' AND expected_accuracy IS NULL;
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS feedback (
+    id SERIAL PRIMARY KEY,
+    request_id VARCHAR(255) UNIQUE NOT NULL REFERENCES inference_requests(request_id),
+    label TEXT,
+    correct BOOLEAN NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX idx_feedback_created_at ON feedback(created_at);
@@ -0,0 +1 @@
+ALTER TABLE inference_requests ADD COLUMN IF NOT EXISTS result TEXT;
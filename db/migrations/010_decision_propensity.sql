@@ -0,0 +1,3 @@
+ALTER TABLE inference_requests ADD COLUMN IF NOT EXISTS strategy VARCHAR(50);
+ALTER TABLE inference_requests ADD COLUMN IF NOT EXISTS propensity FLOAT;
+ALTER TABLE inference_requests ADD COLUMN IF NOT EXISTS bandit_context VARCHAR(255);
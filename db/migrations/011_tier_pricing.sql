@@ -0,0 +1,6 @@
+ALTER TABLE tiers ADD COLUMN IF NOT EXISTS cost_per_1k_tokens_cents FLOAT;
+ALTER TABLE tiers ADD COLUMN IF NOT EXISTS cost_per_kb_cents FLOAT;
+ALTER TABLE tiers ADD COLUMN IF NOT EXISTS cost_per_item_cents FLOAT;
+ALTER TABLE tiers ADD COLUMN IF NOT EXISTS latency_per_1k_tokens_ms FLOAT;
+ALTER TABLE tiers ADD COLUMN IF NOT EXISTS latency_per_kb_ms FLOAT;
+ALTER TABLE tiers ADD COLUMN IF NOT EXISTS latency_per_item_ms FLOAT;
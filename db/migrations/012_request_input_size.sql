@@ -0,0 +1,3 @@
+ALTER TABLE inference_requests ADD COLUMN IF NOT EXISTS input_tokens INTEGER;
+ALTER TABLE inference_requests ADD COLUMN IF NOT EXISTS input_bytes INTEGER;
+ALTER TABLE inference_requests ADD COLUMN IF NOT EXISTS input_items INTEGER;
@@ -0,0 +1,14 @@
+CREATE OR REPLACE FUNCTION notify_config_changed() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('config_changed', TG_TABLE_NAME);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS tiers_config_changed ON tiers;
+CREATE TRIGGER tiers_config_changed AFTER INSERT OR UPDATE OR DELETE ON tiers
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_config_changed();
+
+DROP TRIGGER IF EXISTS policies_config_changed ON policies;
+CREATE TRIGGER policies_config_changed AFTER INSERT OR UPDATE OR DELETE ON policies
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_config_changed();
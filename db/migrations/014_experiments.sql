@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS experiments (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) UNIQUE NOT NULL,
+    definition_json JSONB NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+ALTER TABLE inference_requests ADD COLUMN IF NOT EXISTS experiment VARCHAR(255);
+ALTER TABLE inference_requests ADD COLUMN IF NOT EXISTS arm VARCHAR(255);
+
+CREATE INDEX IF NOT EXISTS idx_inference_requests_experiment ON inference_requests(experiment, created_at);
+
+DROP TRIGGER IF EXISTS experiments_config_changed ON experiments;
+CREATE TRIGGER experiments_config_changed AFTER INSERT OR UPDATE OR DELETE ON experiments
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_config_changed();
@@ -0,0 +1,4 @@
+-- Reservations are keyed on an ID the controlplane generates per request;
+-- request_id comes from the client, which may reuse it.
+ALTER TABLE budget_reservations RENAME COLUMN request_id TO reservation_id;
+ALTER TABLE budget_reservations ADD COLUMN IF NOT EXISTS request_id VARCHAR(255);
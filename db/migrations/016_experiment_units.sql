@@ -0,0 +1,3 @@
+-- The tenant or user ID a request was assigned to its experiment arm by;
+-- the analysis treats requests sharing one as a cluster.
+ALTER TABLE inference_requests ADD COLUMN IF NOT EXISTS experiment_unit VARCHAR(255);
@@ -0,0 +1,3 @@
+-- The tier the cascade entered at, which the logged propensity is for; it
+-- differs from tier when the request escalated.
+ALTER TABLE inference_requests ADD COLUMN IF NOT EXISTS entry_tier VARCHAR(50);
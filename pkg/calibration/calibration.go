@@ -0,0 +1,230 @@
+package calibration
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+const (
+	MethodIsotonic = "isotonic"
+	MethodPlatt    = "platt"
+)
+
+// Sample is a raw worker confidence paired with whether the answer turned
+// out to be correct.
+type Sample struct {
+	Confidence float64
+	Correct    bool
+}
+
+// Map turns a raw confidence into an estimated probability of being correct.
+type Map interface {
+	Apply(confidence float64) float64
+	Method() string
+}
+
+type Identity struct{}
+
+func (Identity) Apply(confidence float64) float64 { return confidence }
+func (Identity) Method() string                   { return "identity" }
+
+func Fit(method string, samples []Sample) (Map, error) {
+	switch method {
+	case MethodIsotonic:
+		return FitIsotonic(samples), nil
+	case MethodPlatt:
+		return FitPlatt(samples), nil
+	default:
+		return nil, fmt.Errorf("unknown calibration method %q", method)
+	}
+}
+
+// Isotonic is a monotone step function fitted with pool-adjacent-violators,
+// interpolated linearly between block centres. Confidences below the
+// observed range are not raised.
+type Isotonic struct {
+	X []float64
+	Y []float64
+}
+
+func FitIsotonic(samples []Sample) *Isotonic {
+	sorted := make([]Sample, len(samples))
+	copy(sorted, samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Confidence < sorted[j].Confidence })
+
+	type block struct {
+		sumX, sumY, n float64
+	}
+	var blocks []block
+	for _, s := range sorted {
+		y := 0.0
+		if s.Correct {
+			y = 1
+		}
+		blocks = append(blocks, block{sumX: s.Confidence, sumY: y, n: 1})
+		for len(blocks) > 1 {
+			last, prev := blocks[len(blocks)-1], blocks[len(blocks)-2]
+			if prev.sumY/prev.n <= last.sumY/last.n {
+				break
+			}
+			blocks = blocks[:len(blocks)-2]
+			blocks = append(blocks, block{sumX: prev.sumX + last.sumX, sumY: prev.sumY + last.sumY, n: prev.n + last.n})
+		}
+	}
+
+	iso := &Isotonic{}
+	for _, b := range blocks {
+		iso.X = append(iso.X, b.sumX/b.n)
+		iso.Y = append(iso.Y, b.sumY/b.n)
+	}
+	return iso
+}
+
+func (m *Isotonic) Apply(confidence float64) float64 {
+	if len(m.X) == 0 {
+		return confidence
+	}
+	if confidence <= m.X[0] {
+		// Nothing was observed this low; the map only vouches for no more
+		// than the lowest block's accuracy.
+		return math.Min(confidence, m.Y[0])
+	}
+	last := len(m.X) - 1
+	if confidence >= m.X[last] {
+		return m.Y[last]
+	}
+	i := sort.SearchFloat64s(m.X, confidence)
+	x0, x1 := m.X[i-1], m.X[i]
+	y0, y1 := m.Y[i-1], m.Y[i]
+	if x1 == x0 {
+		return y1
+	}
+	return y0 + (y1-y0)*(confidence-x0)/(x1-x0)
+}
+
+func (m *Isotonic) Method() string { return MethodIsotonic }
+
+// Platt is a logistic map 1/(1+exp(-(A*confidence+B))).
+type Platt struct {
+	A float64
+	B float64
+}
+
+// FitPlatt fits A and B by Newton's method on the log loss, using Platt's
+// smoothed targets to avoid overconfidence on small samples.
+func FitPlatt(samples []Sample) *Platt {
+	var pos, neg float64
+	for _, s := range samples {
+		if s.Correct {
+			pos++
+		} else {
+			neg++
+		}
+	}
+	hi := (pos + 1) / (pos + 2)
+	lo := 1 / (neg + 2)
+
+	m := &Platt{A: 0, B: math.Log((pos + 1) / (neg + 1))}
+	for iter := 0; iter < 100; iter++ {
+		var ga, gb, haa, hab, hbb float64
+		for _, s := range samples {
+			t := lo
+			if s.Correct {
+				t = hi
+			}
+			p := m.Apply(s.Confidence)
+			d := p - t
+			w := p * (1 - p)
+			ga += d * s.Confidence
+			gb += d
+			haa += w * s.Confidence * s.Confidence
+			hab += w * s.Confidence
+			hbb += w
+		}
+		haa += 1e-6
+		hbb += 1e-6
+		det := haa*hbb - hab*hab
+		if det == 0 {
+			break
+		}
+		da := (hbb*ga - hab*gb) / det
+		db := (haa*gb - hab*ga) / det
+		m.A -= da
+		m.B -= db
+		if math.Abs(da) < 1e-9 && math.Abs(db) < 1e-9 {
+			break
+		}
+	}
+	return m
+}
+
+func (m *Platt) Apply(confidence float64) float64 {
+	return 1 / (1 + math.Exp(-(m.A*confidence + m.B)))
+}
+
+func (m *Platt) Method() string { return MethodPlatt }
+
+// Bin is one bucket of a reliability diagram.
+type Bin struct {
+	Lower          float64 `json:"lower"`
+	Upper          float64 `json:"upper"`
+	Count          int     `json:"count"`
+	MeanConfidence float64 `json:"mean_confidence"`
+	Accuracy       float64 `json:"accuracy"`
+}
+
+// Reliability buckets samples by m's output into equal-width bins. A nil map
+// uses the raw confidences.
+func Reliability(samples []Sample, bins int, m Map) []Bin {
+	if bins <= 0 {
+		bins = 10
+	}
+	if m == nil {
+		m = Identity{}
+	}
+	out := make([]Bin, bins)
+	sumConf := make([]float64, bins)
+	sumCorrect := make([]float64, bins)
+	for i := range out {
+		out[i].Lower = float64(i) / float64(bins)
+		out[i].Upper = float64(i+1) / float64(bins)
+	}
+	for _, s := range samples {
+		c := m.Apply(s.Confidence)
+		i := int(c * float64(bins))
+		if i >= bins {
+			i = bins - 1
+		}
+		if i < 0 {
+			i = 0
+		}
+		out[i].Count++
+		sumConf[i] += c
+		if s.Correct {
+			sumCorrect[i]++
+		}
+	}
+	for i := range out {
+		if out[i].Count > 0 {
+			out[i].MeanConfidence = sumConf[i] / float64(out[i].Count)
+			out[i].Accuracy = sumCorrect[i] / float64(out[i].Count)
+		}
+	}
+	return out
+}
+
+// ExpectedCalibrationError is the count-weighted mean gap between confidence
+// and accuracy across bins.
+func ExpectedCalibrationError(bins []Bin) float64 {
+	var total int
+	var ece float64
+	for _, b := range bins {
+		total += b.Count
+		ece += float64(b.Count) * math.Abs(b.Accuracy-b.MeanConfidence)
+	}
+	if total == 0 {
+		return 0
+	}
+	return ece / float64(total)
+}
@@ -0,0 +1,86 @@
+package calibration
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cost-aware-ml/pkg/decision"
+)
+
+// overconfident produces samples whose true accuracy is half the reported
+// confidence.
+func overconfident() []Sample {
+	var samples []Sample
+	for i := 0; i < 1000; i++ {
+		conf := float64(i%100) / 100
+		samples = append(samples, Sample{Confidence: conf, Correct: float64(i%1000)/1000 < conf/2})
+	}
+	return samples
+}
+
+func TestCalibration(t *testing.T) {
+	samples := overconfident()
+	rawECE := ExpectedCalibrationError(Reliability(samples, 10, nil))
+
+	for _, method := range []string{MethodIsotonic, MethodPlatt} {
+		t.Run(method, func(t *testing.T) {
+			m, err := Fit(method, samples)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.Apply(0.2) > m.Apply(0.8) {
+				t.Errorf("expected monotone map, got %v > %v", m.Apply(0.2), m.Apply(0.8))
+			}
+			ece := ExpectedCalibrationError(Reliability(samples, 10, m))
+			if ece >= rawECE {
+				t.Errorf("expected calibration to reduce ECE below %v, got %v", rawECE, ece)
+			}
+		})
+	}
+
+	if _, err := Fit("unknown", samples); err == nil {
+		t.Error("expected error for unknown method")
+	}
+}
+
+func TestCalibrator(t *testing.T) {
+	c := NewCalibrator(MethodIsotonic, 100)
+	if err := c.Refit(map[string][]Sample{"tier0": overconfident(), "tier1": overconfident()[:10]}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := c.Calibrate("tier0", 0.8); math.Abs(got-0.4) > 0.1 {
+		t.Errorf("expected tier0 0.8 to calibrate near 0.4, got %v", got)
+	}
+	if got := c.Calibrate("tier1", 0.8); got != 0.8 {
+		t.Errorf("expected tier1 below min samples to stay uncalibrated, got %v", got)
+	}
+
+	report := c.Report(10)
+	if report["tier0"].Method != MethodIsotonic || report["tier1"].Method != "identity" {
+		t.Errorf("unexpected report methods: %+v", report)
+	}
+}
+
+func TestEscalationSurvivesCalibration(t *testing.T) {
+	// Answers tier0 kept: confident and right 90% of the time.
+	var kept []Sample
+	for i := 0; i < 200; i++ {
+		kept = append(kept, Sample{Confidence: 0.8 + float64(i%20)/100, Correct: i%10 != 3})
+	}
+	// Answers it escalated, graded per attempt: mostly wrong.
+	escalated := append([]Sample(nil), kept...)
+	for i := 0; i < 200; i++ {
+		escalated = append(escalated, Sample{Confidence: 0.1 + float64(i%40)/100, Correct: i%5 == 0})
+	}
+
+	engine := decision.NewEngine()
+	req := decision.Request{Budget: 10}
+	for name, samples := range map[string][]Sample{"kept only": kept, "every attempt": escalated} {
+		m := FitIsotonic(samples)
+		conf := m.Apply(0.3)
+		if d := engine.DecideAt(decision.Tier0, req, decision.Telemetry{}, conf); d.Tier != decision.Tier1 {
+			t.Errorf("%s: expected a 0.3 tier0 answer (calibrated %.2f) to escalate, got %s (%s)", name, conf, d.Tier, d.Reason)
+		}
+	}
+}
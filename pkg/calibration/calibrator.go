@@ -0,0 +1,90 @@
+package calibration
+
+import (
+	"sync"
+)
+
+// Calibrator holds the current per-tier maps. Tiers with fewer than
+// minSamples labeled outcomes are left uncalibrated.
+type Calibrator struct {
+	mu         sync.RWMutex
+	method     string
+	minSamples int
+	maps       map[string]Map
+	samples    map[string][]Sample
+}
+
+func NewCalibrator(method string, minSamples int) *Calibrator {
+	return &Calibrator{
+		method:     method,
+		minSamples: minSamples,
+		maps:       make(map[string]Map),
+		samples:    make(map[string][]Sample),
+	}
+}
+
+// Refit replaces every tier's map with one fitted from samples.
+func (c *Calibrator) Refit(samples map[string][]Sample) error {
+	maps := make(map[string]Map, len(samples))
+	for tier, s := range samples {
+		if len(s) < c.minSamples {
+			continue
+		}
+		m, err := Fit(c.method, s)
+		if err != nil {
+			return err
+		}
+		maps[tier] = m
+	}
+
+	c.mu.Lock()
+	c.maps = maps
+	c.samples = samples
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *Calibrator) Calibrate(tier string, confidence float64) float64 {
+	c.mu.RLock()
+	m, ok := c.maps[tier]
+	c.mu.RUnlock()
+	if !ok {
+		return confidence
+	}
+	return m.Apply(confidence)
+}
+
+type TierReport struct {
+	Method        string  `json:"method"`
+	Samples       int     `json:"samples"`
+	Raw           []Bin   `json:"raw"`
+	Calibrated    []Bin   `json:"calibrated"`
+	RawECE        float64 `json:"raw_ece"`
+	CalibratedECE float64 `json:"calibrated_ece"`
+}
+
+// Report returns reliability-diagram data for every tier with samples,
+// before and after calibration.
+func (c *Calibrator) Report(bins int) map[string]TierReport {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	report := make(map[string]TierReport, len(c.samples))
+	for tier, s := range c.samples {
+		m, ok := c.maps[tier]
+		if !ok {
+			m = Identity{}
+		}
+		raw := Reliability(s, bins, nil)
+		calibrated := Reliability(s, bins, m)
+		report[tier] = TierReport{
+			Method:        m.Method(),
+			Samples:       len(s),
+			Raw:           raw,
+			Calibrated:    calibrated,
+			RawECE:        ExpectedCalibrationError(raw),
+			CalibratedECE: ExpectedCalibrationError(calibrated),
+		}
+	}
+	return report
+}
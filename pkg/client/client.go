@@ -2,11 +2,16 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"strings"
 	"time"
+
+	"github.com/cost-aware-ml/pkg/deadline"
 )
 
 type WorkerClient struct {
@@ -23,9 +28,13 @@ func New(baseURL string) *WorkerClient {
 	}
 }
 
+// ErrDeadlineExceeded is returned when the worker refuses a call it cannot
+// answer before the request's deadline.
+var ErrDeadlineExceeded = fmt.Errorf("worker: %w", context.DeadlineExceeded)
+
 type InferRequest struct {
 	RequestID string      `json:"request_id"`
-	Payload   interface{} `json:"payload"`
+	Input     interface{} `json:"input"`
 }
 
 type InferResponse struct {
@@ -35,17 +44,33 @@ type InferResponse struct {
 }
 
 func (c *WorkerClient) Infer(req InferRequest) (*InferResponse, error) {
+	return c.InferContext(context.Background(), req)
+}
+
+// InferContext is Infer with a context that can cancel the call; its
+// deadline, if any, is passed on to the worker.
+func (c *WorkerClient) InferContext(ctx context.Context, req InferRequest) (*InferResponse, error) {
 	body, err := json.Marshal(req)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := c.client.Post(c.baseURL+"/infer", "application/json", bytes.NewBuffer(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/infer", bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	deadline.Inject(ctx, httpReq.Header)
+
+	resp, err := c.client.Do(httpReq)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusGatewayTimeout {
+		return nil, ErrDeadlineExceeded
+	}
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
 		return nil, fmt.Errorf("worker error: %s", string(body))
@@ -59,3 +84,12 @@ func (c *WorkerClient) Infer(req InferRequest) (*InferResponse, error) {
 	return &result, nil
 }
 
+
+// URLFor returns the worker URL for a tier, preferring <TIER>_URL from the
+// environment (e.g. TIER0_URL) over the configured one.
+func URLFor(tier, configured string) string {
+	if url := os.Getenv(strings.ToUpper(tier) + "_URL"); url != "" {
+		return url
+	}
+	return configured
+}
@@ -0,0 +1,25 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestInferSendsInput(t *testing.T) {
+	var got map[string]interface{}
+	worker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		json.NewEncoder(w).Encode(InferResponse{Result: "ok", Confidence: 0.9})
+	}))
+	defer worker.Close()
+
+	if _, err := New(worker.URL).Infer(InferRequest{RequestID: "r1", Input: "classify me"}); err != nil {
+		t.Fatal(err)
+	}
+	// The workers read the text to score from the "input" field.
+	if got["input"] != "classify me" {
+		t.Errorf("expected the worker to receive the input, got %v", got)
+	}
+}
@@ -0,0 +1,52 @@
+package deadline
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Header carries the milliseconds a request has left. Each hop sends what
+// remains of its own deadline, so time spent upstream is already deducted
+// and hops never compare clocks.
+const Header = "X-Deadline-Ms"
+
+// FromHeader returns ctx bounded by the time left in h, if h carries any.
+func FromHeader(ctx context.Context, h http.Header) (context.Context, context.CancelFunc) {
+	ms, err := strconv.Atoi(h.Get(Header))
+	if err != nil {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, time.Duration(ms)*time.Millisecond)
+}
+
+// WithBudget bounds ctx by a latency budget in milliseconds; zero or less
+// leaves ctx unbounded.
+func WithBudget(ctx context.Context, ms int) (context.Context, context.CancelFunc) {
+	if ms <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, time.Duration(ms)*time.Millisecond)
+}
+
+// Inject sets Header to the time left before ctx's deadline, if it has one.
+func Inject(ctx context.Context, h http.Header) {
+	if ms, ok := Remaining(ctx); ok {
+		h.Set(Header, strconv.Itoa(ms))
+	}
+}
+
+// Remaining is the whole milliseconds left before ctx's deadline, never
+// negative; ok is false when ctx has no deadline.
+func Remaining(ctx context.Context) (int, bool) {
+	d, ok := ctx.Deadline()
+	if !ok {
+		return 0, false
+	}
+	ms := int(time.Until(d).Milliseconds())
+	if ms < 0 {
+		ms = 0
+	}
+	return ms, true
+}
@@ -0,0 +1,39 @@
+package deadline
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestPropagation(t *testing.T) {
+	ctx, cancel := WithBudget(context.Background(), 500)
+	defer cancel()
+	time.Sleep(20 * time.Millisecond)
+
+	h := http.Header{}
+	Inject(ctx, h)
+	ms, err := time.ParseDuration(h.Get(Header) + "ms")
+	if err != nil || ms > 485*time.Millisecond || ms < 300*time.Millisecond {
+		t.Fatalf("expected the header to carry the time left after 20ms, got %q", h.Get(Header))
+	}
+
+	next, cancel := FromHeader(context.Background(), h)
+	defer cancel()
+	if left, ok := Remaining(next); !ok || left > 485 {
+		t.Errorf("expected the next hop to inherit the deadline, got %d (%v)", left, ok)
+	}
+
+	plain, cancel := FromHeader(context.Background(), http.Header{})
+	defer cancel()
+	if _, ok := Remaining(plain); ok {
+		t.Error("expected no deadline without the header")
+	}
+
+	unbounded, cancel := WithBudget(context.Background(), 0)
+	defer cancel()
+	if _, ok := unbounded.Deadline(); ok {
+		t.Error("expected a zero budget to leave the context unbounded")
+	}
+}
@@ -0,0 +1,374 @@
+package decision
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	BanditEpsilonGreedy = "epsilon_greedy"
+	BanditThompson      = "thompson"
+
+	defaultEpsilon = 0.1
+	thompsonDraws  = 200
+	rewardFloor    = 1e-3
+)
+
+// ArmStats is what the bandit has learned about one tier in one context.
+// Alpha and Beta parameterize the Beta posterior used by Thompson sampling;
+// fractional rewards add r successes and 1-r failures.
+type ArmStats struct {
+	Pulls     float64 `json:"pulls"`
+	RewardSum float64 `json:"reward_sum"`
+	Alpha     float64 `json:"alpha"`
+	Beta      float64 `json:"beta"`
+}
+
+func (a *ArmStats) mean() float64 {
+	if a.Pulls == 0 {
+		return 0
+	}
+	return a.RewardSum / a.Pulls
+}
+
+// Bandit picks a tier per context bucket and learns from rewards in [0, 1].
+// It is safe for concurrent use.
+type Bandit struct {
+	mu   sync.Mutex
+	rng  *rand.Rand
+	arms map[string]map[Tier]*ArmStats
+}
+
+func NewBandit(seed int64) *Bandit {
+	return &Bandit{
+		rng:  rand.New(rand.NewSource(seed)),
+		arms: make(map[string]map[Tier]*ArmStats),
+	}
+}
+
+func (b *Bandit) arm(context string, tier Tier) *ArmStats {
+	ctxArms, ok := b.arms[context]
+	if !ok {
+		ctxArms = make(map[Tier]*ArmStats)
+		b.arms[context] = ctxArms
+	}
+	a, ok := ctxArms[tier]
+	if !ok {
+		a = &ArmStats{Alpha: 1, Beta: 1}
+		ctxArms[tier] = a
+	}
+	return a
+}
+
+// Choose picks one of tiers for context and returns it with the probability
+// it had of being picked.
+func (b *Bandit) Choose(context string, tiers []Tier, algorithm string, epsilon float64) (Tier, float64, bool) {
+	if len(tiers) == 0 {
+		return "", 0, false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if algorithm == BanditThompson {
+		return b.thompson(context, tiers)
+	}
+	return b.epsilonGreedy(context, tiers, epsilon)
+}
+
+func (b *Bandit) epsilonGreedy(context string, tiers []Tier, epsilon float64) (Tier, float64, bool) {
+	greedy := 0
+	for i, t := range tiers {
+		a := b.arm(context, t)
+		if a.Pulls == 0 {
+			// Try every arm once before trusting the means.
+			greedy = i
+			break
+		}
+		if a.mean() > b.arm(context, tiers[greedy]).mean() {
+			greedy = i
+		}
+	}
+
+	explore := epsilon / float64(len(tiers))
+	chosen := greedy
+	explored := b.rng.Float64() < epsilon
+	if explored {
+		chosen = b.rng.Intn(len(tiers))
+	}
+
+	propensity := explore
+	if chosen == greedy {
+		propensity += 1 - epsilon
+	}
+	return tiers[chosen], propensity, explored && chosen != greedy
+}
+
+func (b *Bandit) thompson(context string, tiers []Tier) (Tier, float64, bool) {
+	draw := func() int {
+		best, bestSample := 0, -1.0
+		for i, t := range tiers {
+			a := b.arm(context, t)
+			sample := betaSample(b.rng, a.Alpha, a.Beta)
+			if sample > bestSample {
+				best, bestSample = i, sample
+			}
+		}
+		return best
+	}
+
+	chosen := draw()
+
+	// The propensity of a Thompson draw has no closed form; estimate it by
+	// repeating the draw.
+	wins := 0
+	for i := 0; i < thompsonDraws; i++ {
+		if draw() == chosen {
+			wins++
+		}
+	}
+	propensity := (float64(wins) + 1) / float64(thompsonDraws+len(tiers))
+
+	greedy := 0
+	for i, t := range tiers {
+		a, g := b.arm(context, t), b.arm(context, tiers[greedy])
+		if a.Alpha/(a.Alpha+a.Beta) > g.Alpha/(g.Alpha+g.Beta) {
+			greedy = i
+		}
+	}
+	return tiers[chosen], propensity, chosen != greedy
+}
+
+// Update records one pull of tier in context with the given reward.
+func (b *Bandit) Update(context string, tier Tier, reward float64) {
+	reward = clamp01(reward)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	a := b.arm(context, tier)
+	a.Pulls++
+	a.RewardSum += reward
+	a.Alpha += reward
+	a.Beta += 1 - reward
+}
+
+// Correct replaces a reward recorded earlier by Update, e.g. a confidence
+// proxy once ground-truth feedback arrives, without counting another pull.
+func (b *Bandit) Correct(context string, tier Tier, previous, reward float64) {
+	delta := clamp01(reward) - clamp01(previous)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	a := b.arm(context, tier)
+	a.RewardSum += delta
+	a.Alpha = math.Max(a.Alpha+delta, rewardFloor)
+	a.Beta = math.Max(a.Beta-delta, rewardFloor)
+}
+
+// Snapshot returns a copy of every arm's statistics keyed by context.
+func (b *Bandit) Snapshot() map[string]map[Tier]ArmStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	out := make(map[string]map[Tier]ArmStats, len(b.arms))
+	for ctx, arms := range b.arms {
+		out[ctx] = make(map[Tier]ArmStats, len(arms))
+		for tier, a := range arms {
+			out[ctx][tier] = *a
+		}
+	}
+	return out
+}
+
+// Bandit returns the engine's bandit so its state can be inspected or
+// carried over to a rebuilt engine.
+func (e *Engine) Bandit() *Bandit {
+	return e.bandit
+}
+
+// UseBandit replaces the engine's bandit.
+func (e *Engine) UseBandit(b *Bandit) {
+	e.bandit = b
+}
+
+// enterBandit picks the arm to serve req from among the tiers it can
+// afford within its latency SLO.
+func (e *Engine) enterBandit(entry Decision, cascade []TierConfig, req Request, telemetry Telemetry, x *Explanation) Decision {
+	if !x.check("monthly_budget_available", "", boolValue(!req.BudgetExhausted), 1, !req.BudgetExhausted) {
+		entry.Reason = "monthly_budget_exhausted"
+		return entry
+	}
+
+	budget := EffectiveBudget(req)
+	maxLatencyMS := maxLatency(req)
+	arms := make([]Tier, 0, len(cascade))
+	for _, t := range cascade {
+		cost := EstimateCost(t, req)
+		if !x.check("budget_covers_tier", t.Name, budget, cost, cost <= budget) {
+			continue
+		}
+		if !x.check("tier_not_saturated", t.Name, float64(telemetry.QueueDepth[t.Name]), float64(t.MaxConcurrency), !saturated(t, telemetry)) {
+			continue
+		}
+		latencyMS := expectedLatency(t, req, telemetry)
+		if maxLatencyMS > 0 && !x.check("latency_within_slo", t.Name, float64(latencyMS), float64(maxLatencyMS), latencyMS <= maxLatencyMS) {
+			continue
+		}
+		if left, ok := remainingMS(req); ok && !x.check("deadline_allows", t.Name, float64(latencyMS), float64(left), latencyMS <= left) {
+			continue
+		}
+		arms = append(arms, t.Name)
+	}
+	if len(arms) == 0 {
+		entry.Reason = "no_eligible_arm"
+		return entry
+	}
+
+	tier, propensity, explored := e.bandit.Choose(entry.Context, arms, req.Policy.banditAlgorithm(), req.Policy.epsilon())
+	x.check("bandit_explored", tier, propensity, req.Policy.epsilon(), explored)
+	i := indexOf(cascade, tier)
+	t := cascade[i]
+	reason := "bandit_exploit"
+	if explored {
+		reason = "bandit_explore"
+	}
+	return Decision{
+		Tier:                t.Name,
+		Reason:              reason,
+		EstimatedCost:       EstimateCost(t, req),
+		EstimatedLatency:    expectedLatency(t, req, telemetry),
+		ConfidenceThreshold: confidenceThreshold(t, i, req),
+		Strategy:            StrategyBandit,
+		Propensity:          propensity,
+		Context:             entry.Context,
+		Explanation:         x,
+	}
+}
+
+// decideBandit keeps the arm's answer: the bandit learns from what the arm
+// returned, so escalating would hide the outcome of its choice.
+func (e *Engine) decideBandit(current Tier, req Request, x *Explanation) Decision {
+	cascade := e.Cascade(req.Policy)
+	i := indexOf(cascade, current)
+	if !x.check("tier_allowed", current, 0, 0, i >= 0) {
+		return Decision{Tier: current, Reason: "tier_not_allowed"}
+	}
+	cur := cascade[i]
+	return Decision{
+		Tier:                cur.Name,
+		Reason:              "bandit_arm",
+		EstimatedCost:       EstimateCost(cur, req),
+		EstimatedLatency:    estimateLatency(cur, req),
+		ConfidenceThreshold: confidenceThreshold(cur, i, req),
+		Context:             BanditContext(req),
+	}
+}
+
+// Reward scores an answer served for req using the policy's cost weight,
+// with cost measured against the most expensive tier in its cascade for
+// req's input.
+func (e *Engine) Reward(req Request, quality, costCents float64) float64 {
+	return e.RewardFor(req.Policy, req.features(), quality, costCents)
+}
+
+// RewardFor is Reward for a request known only by its input's features, as
+// logged with the decision.
+func (e *Engine) RewardFor(policy *Policy, input InputFeatures, quality, costCents float64) float64 {
+	maxCost := 0.0
+	for _, t := range e.Cascade(policy) {
+		if cost := t.Cost(input); cost > maxCost {
+			maxCost = cost
+		}
+	}
+	return BanditReward(quality, costCents, maxCost, policy.costWeight())
+}
+
+// BanditContext buckets a request by tenant, priority and input size,
+// unless the request carries its context.
+func BanditContext(req Request) string {
+	if req.Context != "" {
+		return req.Context
+	}
+	priority := req.Priority
+	if priority == "" {
+		priority = "normal"
+	}
+	return fmt.Sprintf("%s|%s|%s", req.TenantID, priority, InputSizeBucket(req.Input))
+}
+
+// InputSizeBucket mirrors the length bands tier0's confidence depends on.
+// Non-string inputs are measured by their JSON encoding.
+func InputSizeBucket(input interface{}) string {
+	n := 0
+	switch v := input.(type) {
+	case string:
+		n = len(v)
+	case nil:
+	default:
+		data, _ := json.Marshal(v)
+		n = len(data)
+	}
+	switch {
+	case n < 10:
+		return "lt10"
+	case n < 50:
+		return "lt50"
+	case n < 100:
+		return "lt100"
+	default:
+		return "ge100"
+	}
+}
+
+// BanditReward scores a served answer: its quality (1/0 from feedback or a
+// calibrated confidence) minus the cost weight times its share of maxCost.
+func BanditReward(quality, costCents, maxCostCents, costWeight float64) float64 {
+	penalty := 0.0
+	if maxCostCents > 0 {
+		penalty = costWeight * costCents / maxCostCents
+	}
+	return clamp01((1-costWeight)*quality + costWeight - penalty)
+}
+
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
+
+// betaSample draws from Beta(alpha, beta) as a ratio of gamma draws.
+func betaSample(rng *rand.Rand, alpha, beta float64) float64 {
+	x := gammaSample(rng, alpha)
+	y := gammaSample(rng, beta)
+	if x+y == 0 {
+		return 0.5
+	}
+	return x / (x + y)
+}
+
+// gammaSample uses Marsaglia and Tsang's method, boosting shapes below one.
+func gammaSample(rng *rand.Rand, shape float64) float64 {
+	if shape < 1 {
+		u := rng.Float64()
+		return gammaSample(rng, shape+1) * math.Pow(u, 1/shape)
+	}
+	d := shape - 1.0/3
+	c := 1 / math.Sqrt(9*d)
+	for {
+		x := rng.NormFloat64()
+		v := 1 + c*x
+		if v <= 0 {
+			continue
+		}
+		v = v * v * v
+		u := rng.Float64()
+		if u < 1-0.0331*x*x*x*x || math.Log(u) < 0.5*x*x+d*(1-v+math.Log(v)) {
+			return d * v
+		}
+	}
+}
+
+func newBanditSeed() int64 {
+	return time.Now().UnixNano()
+}
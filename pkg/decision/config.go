@@ -0,0 +1,142 @@
+package decision
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// Config is the part of the engine that can be replaced at runtime, in the
+// format of the ENGINE_CONFIG file.
+type Config struct {
+	Tiers []TierConfig `json:"tiers"`
+}
+
+type ConfigError struct {
+	Field   string
+	Message string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("invalid config: %s: %s", e.Field, e.Message)
+}
+
+func ParseConfig(data []byte) (*Config, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	var c Config
+	if err := dec.Decode(&c); err != nil {
+		return nil, &ConfigError{Field: "config", Message: err.Error()}
+	}
+	if err := ValidateTiers(c.Tiers); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// ValidateTiers checks that tiers can run a cascade: names are unique, at
+// least one tier is enabled, and costs, timeouts and thresholds are in range.
+func ValidateTiers(tiers []TierConfig) error {
+	if len(tiers) == 0 {
+		return &ConfigError{Field: "tiers", Message: "must not be empty"}
+	}
+	seen := make(map[Tier]bool, len(tiers))
+	enabled := 0
+	for i, t := range tiers {
+		field := fmt.Sprintf("tiers[%d]", i)
+		if t.Name == "" {
+			return &ConfigError{Field: field + ".name", Message: "must not be empty"}
+		}
+		if seen[t.Name] {
+			return &ConfigError{Field: field + ".name", Message: fmt.Sprintf("duplicate tier %q", t.Name)}
+		}
+		seen[t.Name] = true
+		if t.BaseCostCents < 0 {
+			return &ConfigError{Field: field + ".base_cost_cents", Message: "must not be negative"}
+		}
+		if t.TimeoutMS <= 0 {
+			return &ConfigError{Field: field + ".timeout_ms", Message: "must be positive"}
+		}
+		if t.MaxConcurrency < 0 {
+			return &ConfigError{Field: field + ".max_concurrency", Message: "must not be negative"}
+		}
+		if t.DefaultConfThreshold < 0 || t.DefaultConfThreshold > 1 {
+			return &ConfigError{Field: field + ".default_conf_threshold", Message: "must be between 0 and 1"}
+		}
+		if t.MaxErrorRate < 0 || t.MaxErrorRate > 1 {
+			return &ConfigError{Field: field + ".max_error_rate", Message: "must be between 0 and 1"}
+		}
+		if t.ExpectedAccuracy < 0 || t.ExpectedAccuracy > 1 {
+			return &ConfigError{Field: field + ".expected_accuracy", Message: "must be between 0 and 1"}
+		}
+		p := t.Pricing
+		if p.CostPer1KTokensCents < 0 || p.CostPerKBCents < 0 || p.CostPerItemCents < 0 ||
+			p.LatencyPer1KTokensMS < 0 || p.LatencyPerKBMS < 0 || p.LatencyPerItemMS < 0 {
+			return &ConfigError{Field: field + ".pricing", Message: "coefficients must not be negative"}
+		}
+		if t.Enabled {
+			enabled++
+		}
+	}
+	if enabled == 0 {
+		return &ConfigError{Field: "tiers", Message: "at least one tier must be enabled"}
+	}
+	return nil
+}
+
+// LiveEngine holds the engine in use and swaps it atomically when the
+// config changes. Callers Load the engine once per request, so decisions in
+// flight finish on the config they started with.
+type LiveEngine struct {
+	mu      sync.Mutex
+	current atomic.Pointer[Engine]
+	tiers   []TierConfig
+}
+
+// NewLiveEngine serves an engine built from tiers as config version 1.
+func NewLiveEngine(tiers []TierConfig) *LiveEngine {
+	e := NewEngineWithTiers(tiers)
+	e.version = 1
+	l := &LiveEngine{tiers: tiers}
+	l.current.Store(e)
+	return l
+}
+
+func (l *LiveEngine) Load() *Engine {
+	return l.current.Load()
+}
+
+// Swap validates tiers and installs an engine built from them under the
+// next config version. The bandit's statistics carry over. Tiers equal to
+// the ones in use leave the engine and version as they are.
+func (l *LiveEngine) Swap(tiers []TierConfig) (*Engine, error) {
+	if err := ValidateTiers(tiers); err != nil {
+		return nil, err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	old := l.current.Load()
+	if reflect.DeepEqual(tiers, l.tiers) {
+		return old, nil
+	}
+	e := NewEngineWithTiers(tiers)
+	e.UseBandit(old.Bandit())
+	e.version = old.version + 1
+	l.tiers = tiers
+	l.current.Store(e)
+	return e, nil
+}
+
+// WithTiers builds an engine over tiers that shares e's bandit and reports
+// e's config version, for requests that run on a variant of the live config.
+func (e *Engine) WithTiers(tiers []TierConfig) *Engine {
+	v := NewEngineWithTiers(tiers)
+	v.bandit = e.bandit
+	v.version = e.version
+	return v
+}
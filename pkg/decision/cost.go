@@ -0,0 +1,46 @@
+package decision
+
+const (
+	OutcomeOK          = "ok"
+	OutcomeError       = "error"
+	OutcomeCircuitOpen = "circuit_open"
+	// OutcomeCanceled is a hedged call abandoned once the other call won.
+	OutcomeCanceled = "canceled"
+)
+
+// Attempt records one tier call made while serving a request.
+type Attempt struct {
+	Tier                 Tier    `json:"tier"`
+	Outcome              string  `json:"outcome"`
+	Confidence           float64 `json:"confidence,omitempty"`
+	CalibratedConfidence float64 `json:"calibrated_confidence,omitempty"`
+	// Result is the tier's answer, kept so feedback can grade every
+	// attempt and not only the one served.
+	Result    string  `json:"result,omitempty"`
+	LatencyMS int     `json:"latency_ms"`
+	CostCents float64 `json:"cost_cents"`
+}
+
+// AttemptCost is what a call to t for req costs given its outcome:
+// successful calls and canceled hedges (the worker has already started) are
+// always billed, failed calls only if the tier bills failures, and calls
+// rejected by an open circuit never reach the worker.
+func AttemptCost(t TierConfig, req Request, outcome string) float64 {
+	switch outcome {
+	case OutcomeOK, OutcomeCanceled:
+		return EstimateCost(t, req)
+	case OutcomeError:
+		if t.BillFailedAttempts {
+			return EstimateCost(t, req)
+		}
+	}
+	return 0
+}
+
+func TotalCost(attempts []Attempt) float64 {
+	var total float64
+	for _, a := range attempts {
+		total += a.CostCents
+	}
+	return total
+}
@@ -1,5 +1,10 @@
 package decision
 
+import (
+	"fmt"
+	"time"
+)
+
 type Tier string
 
 const (
@@ -8,6 +13,16 @@ const (
 	Tier2 Tier = "tier2"
 )
 
+const (
+	defaultBudgetCents   = 10.0
+	premiumConfThreshold = 0.70
+	// busyLoad is the share of a tier's concurrency in use above which the
+	// engine keeps an answer within busySlack of its threshold rather than
+	// queue behind the tier's backlog.
+	busyLoad  = 0.8
+	busySlack = 0.05
+)
+
 type Request struct {
 	RequestID    string
 	UserID       string
@@ -17,157 +32,503 @@ type Request struct {
 	MaxLatencyMS int
 	MaxCostCents float64
 	Budget       float64
+	// DefaultBudget is the tenant's per-request budget, used when the
+	// request carries neither Budget nor MaxCostCents.
+	DefaultBudget float64
+	// BudgetExhausted pins the request to the entry tier once the tenant's
+	// monthly budget is spent.
+	BudgetExhausted bool
+	// Deadline is when the caller stops waiting for an answer; escalations
+	// that cannot finish before it are skipped. Zero means no deadline.
+	Deadline time.Time
+	// EntryHint is the tier a pre-routing prediction suggests starting at
+	// for inputs the cheaper tiers are likely to fail on.
+	EntryHint Tier
+	// EntryTier is the tier the cascade entered at; MaxEscalations counts
+	// from it. Empty counts from the bottom of the cascade.
+	EntryTier Tier
+	// Context, when set, is the bandit context to decide in instead of the
+	// one bucketed from Input; replays of logged requests, whose input is
+	// not kept, set it to the logged context.
+	Context string
+	// Features, when set, stands in for the features measured from Input,
+	// for the same replays.
+	Features *InputFeatures
+	Policy   *Policy
 }
 
 type TierConfig struct {
-	Name                Tier
-	BaseCostCents       float64
-	TimeoutMS           int
-	DefaultConfThreshold float64
-	Enabled             bool
+	Name                 Tier    `json:"name"`
+	URL                  string  `json:"url,omitempty"`
+	BaseCostCents        float64 `json:"base_cost_cents"`
+	TimeoutMS            int     `json:"timeout_ms"`
+	MaxConcurrency       int     `json:"max_concurrency,omitempty"`
+	DefaultConfThreshold float64 `json:"default_conf_threshold"`
+	MaxErrorRate         float64 `json:"max_error_rate,omitempty"`
+	ExpectedAccuracy     float64 `json:"expected_accuracy,omitempty"`
+	BillFailedAttempts   bool    `json:"bill_failed_attempts,omitempty"`
+	Enabled              bool    `json:"enabled"`
+	Pricing              Pricing `json:"pricing"`
 }
 
 type Telemetry struct {
-	P99LatencyMS map[Tier]int
-	ErrorRate    map[Tier]float64
-	QueueDepth   map[Tier]int
+	P95LatencyMS map[Tier]int     `json:"p95_latency_ms,omitempty"`
+	P99LatencyMS map[Tier]int     `json:"p99_latency_ms,omitempty"`
+	ErrorRate    map[Tier]float64 `json:"error_rate,omitempty"`
+	QueueDepth   map[Tier]int     `json:"queue_depth,omitempty"`
+	// PredictedLatencyMS is the P99 the latency model predicts for this
+	// request's input on each tier; it replaces the global P99.
+	PredictedLatencyMS map[Tier]int `json:"predicted_latency_ms,omitempty"`
 }
 
 type Decision struct {
-	Tier            Tier
-	Reason          string
-	EstimatedCost   float64
-	EstimatedLatency int
+	Tier                Tier
+	Reason              string
+	EstimatedCost       float64
+	EstimatedLatency    int
 	ConfidenceThreshold float64
+	Strategy            string
+	// Propensity is the probability the routing policy had of picking Tier;
+	// deterministic strategies log 1.
+	Propensity float64
+	// Context is the tenant|priority|input-size bucket the choice was made
+	// in; the bandit keeps separate arms per bucket.
+	Context string
+	// ConfigVersion is the version of the engine config the decision was
+	// made with.
+	ConfigVersion int64
+	Explanation   *Explanation
 }
 
 type Engine struct {
-	tiers map[Tier]TierConfig
+	tiers []TierConfig
+	// configured names every tier in the config, disabled ones included.
+	configured []Tier
+	bandit     *Bandit
+	version    int64
+}
+
+// DefaultTiers mirrors the seed rows of the tiers table and is used when
+// the table cannot be read.
+func DefaultTiers() []TierConfig {
+	return []TierConfig{
+		{Name: Tier0, URL: "http://tier0-fast:8090", BaseCostCents: 0.5, TimeoutMS: 50, MaxConcurrency: 100, DefaultConfThreshold: 0.75, ExpectedAccuracy: 0.72, Enabled: true},
+		{Name: Tier1, URL: "http://tier1-mid:8091", BaseCostCents: 2.0, TimeoutMS: 200, MaxConcurrency: 50, DefaultConfThreshold: 0.85, MaxErrorRate: 0.1, ExpectedAccuracy: 0.88, Enabled: true},
+		{Name: Tier2, URL: "http://tier2-best:8092", BaseCostCents: 5.0, TimeoutMS: 500, MaxConcurrency: 20, DefaultConfThreshold: 0.95, MaxErrorRate: 0.15, ExpectedAccuracy: 0.96, Enabled: true},
+	}
 }
 
 func NewEngine() *Engine {
-	return &Engine{
-		tiers: map[Tier]TierConfig{
-			Tier0: {Name: Tier0, BaseCostCents: 0.5, TimeoutMS: 50, DefaultConfThreshold: 0.75, Enabled: true},
-			Tier1: {Name: Tier1, BaseCostCents: 2.0, TimeoutMS: 200, DefaultConfThreshold: 0.85, Enabled: true},
-			Tier2: {Name: Tier2, BaseCostCents: 5.0, TimeoutMS: 500, DefaultConfThreshold: 0.95, Enabled: true},
-		},
+	return NewEngineWithTiers(DefaultTiers())
+}
+
+// NewEngineWithTiers builds an engine over tiers ordered from cheapest to
+// most expensive. Disabled tiers are dropped from the cascade.
+func NewEngineWithTiers(tiers []TierConfig) *Engine {
+	enabled := make([]TierConfig, 0, len(tiers))
+	configured := make([]Tier, 0, len(tiers))
+	for _, t := range tiers {
+		if t.Enabled {
+			enabled = append(enabled, t)
+		}
+		configured = append(configured, t.Name)
 	}
+	return &Engine{tiers: enabled, configured: configured, bandit: NewBandit(newBanditSeed())}
+}
+
+func (e *Engine) Tiers() []TierConfig {
+	tiers := make([]TierConfig, len(e.tiers))
+	copy(tiers, e.tiers)
+	return tiers
+}
+
+// Version is the config version the engine was built from; engines not
+// installed through a LiveEngine report 0.
+func (e *Engine) Version() int64 {
+	return e.version
 }
 
-func (e *Engine) Decide(req Request, telemetry Telemetry, tier0Confidence float64) Decision {
-	if !e.tiers[Tier0].Enabled {
-		return Decision{Tier: Tier1, Reason: "tier0 disabled"}
+func (e *Engine) TierNames() []Tier {
+	names := make([]Tier, len(e.tiers))
+	for i, t := range e.tiers {
+		names[i] = t.Name
 	}
+	return names
+}
 
-	budget := req.Budget
-	if budget == 0 {
-		budget = req.MaxCostCents
+// ConfiguredTierNames names every configured tier, enabled or not. Policies
+// are validated against it so that disabling a tier does not invalidate the
+// policies that name it; the cascade skips the tier until it is re-enabled.
+func (e *Engine) ConfiguredTierNames() []Tier {
+	names := make([]Tier, len(e.configured))
+	copy(names, e.configured)
+	return names
+}
+
+// Cascade returns the tiers a request governed by p may escalate through,
+// cheapest first.
+func (e *Engine) Cascade(p *Policy) []TierConfig {
+	tiers := make([]TierConfig, 0, len(e.tiers))
+	for _, t := range e.tiers {
+		if p.allows(t.Name) {
+			tiers = append(tiers, t)
+		}
 	}
-	if budget == 0 {
-		budget = 10.0
+	return tiers
+}
+
+// First returns the entry tier of the cascade.
+func (e *Engine) First(p *Policy) (TierConfig, bool) {
+	cascade := e.Cascade(p)
+	if len(cascade) == 0 {
+		return TierConfig{}, false
 	}
+	return cascade[0], true
+}
 
-	confThreshold := e.tiers[Tier0].DefaultConfThreshold
-	if req.Priority == "premium" {
-		confThreshold = 0.70
+func (e *Engine) Config(tier Tier) (TierConfig, bool) {
+	for _, t := range e.tiers {
+		if t.Name == tier {
+			return t, true
+		}
 	}
+	return TierConfig{}, false
+}
 
-	if tier0Confidence >= confThreshold {
-		return Decision{
-			Tier:            Tier0,
-			Reason:          "confidence_met",
-			EstimatedCost:   e.tiers[Tier0].BaseCostCents,
-			EstimatedLatency: e.tiers[Tier0].TimeoutMS,
-			ConfidenceThreshold: confThreshold,
+// Next returns the tier the cascade escalates to from tier.
+func (e *Engine) Next(tier Tier, p *Policy) (TierConfig, bool) {
+	cascade := e.Cascade(p)
+	i := indexOf(cascade, tier)
+	if i < 0 || i+1 >= len(cascade) {
+		return TierConfig{}, false
+	}
+	return cascade[i+1], true
+}
+
+// escalations is how many times a cascade that entered at req.EntryTier has
+// escalated to reach cascade[i].
+func escalations(cascade []TierConfig, i int, req Request) int {
+	if entry := indexOf(cascade, req.EntryTier); entry >= 0 && entry <= i {
+		return i - entry
+	}
+	return i
+}
+
+// spent is the estimated cost of the serial cascade from req's entry tier
+// up to and including cascade[i].
+func spent(cascade []TierConfig, i int, req Request) float64 {
+	total := 0.0
+	for _, t := range cascade[i-escalations(cascade, i, req) : i+1] {
+		total += EstimateCost(t, req)
+	}
+	return total
+}
+
+func indexOf(tiers []TierConfig, tier Tier) int {
+	for i, t := range tiers {
+		if t.Name == tier {
+			return i
 		}
 	}
+	return -1
+}
+
+// Entry picks the first tier to call. The threshold and utility strategies
+// start at the bottom of the cascade, moving past tiers that are saturated
+// when the next one is affordable and within the latency SLO, or straight to
+// the request's EntryHint under the same conditions; the bandit strategy
+// picks an arm and serves from it without escalating.
+func (e *Engine) Entry(req Request, telemetry Telemetry) Decision {
+	cascade := e.Cascade(req.Policy)
+	if len(cascade) == 0 {
+		return Decision{Reason: "no_tiers_enabled", Strategy: req.Policy.strategy(), ConfigVersion: e.version}
+	}
+	x := newExplanation("", req, telemetry, 0)
+	entry := e.entryAt(cascade, 0, "entry", req, telemetry, x)
 
-	if budget < e.tiers[Tier1].BaseCostCents || !e.tiers[Tier1].Enabled {
-		return Decision{
-			Tier:            Tier0,
-			Reason:          "budget_too_low",
-			EstimatedCost:   e.tiers[Tier0].BaseCostCents,
-			EstimatedLatency: e.tiers[Tier0].TimeoutMS,
-			ConfidenceThreshold: confThreshold,
+	var d Decision
+	if req.Policy.strategy() == StrategyBandit {
+		d = e.enterBandit(entry, cascade, req, telemetry, x)
+	} else {
+		d = e.enterCascade(entry, cascade, req, telemetry, x)
+		if req.EntryHint != "" {
+			d = e.enterHint(d, cascade, req, telemetry, x)
 		}
 	}
+	x.Decision, x.Reason = d.Tier, d.Reason
+	d.ConfigVersion = e.version
+	return d
+}
 
-	if tier0Confidence < confThreshold && budget >= e.tiers[Tier1].BaseCostCents {
-		latencyMS := e.tiers[Tier1].TimeoutMS
-		if telemetry.P99LatencyMS[Tier1] > 0 {
-			latencyMS = telemetry.P99LatencyMS[Tier1]
+func (e *Engine) entryAt(cascade []TierConfig, i int, reason string, req Request, telemetry Telemetry, x *Explanation) Decision {
+	t := cascade[i]
+	return Decision{
+		Tier:                t.Name,
+		Reason:              reason,
+		EstimatedCost:       EstimateCost(t, req),
+		EstimatedLatency:    expectedLatency(t, req, telemetry),
+		ConfidenceThreshold: confidenceThreshold(t, i, req),
+		Strategy:            req.Policy.strategy(),
+		Propensity:          1,
+		Context:             BanditContext(req),
+		Explanation:         x,
+	}
+}
+
+func (e *Engine) enterCascade(entry Decision, cascade []TierConfig, req Request, telemetry Telemetry, x *Explanation) Decision {
+	budget := EffectiveBudget(req)
+	maxLatencyMS := maxLatency(req)
+	for i := 0; i+1 < len(cascade); i++ {
+		t, next := cascade[i], cascade[i+1]
+		if x.check("tier_not_saturated", t.Name, float64(telemetry.QueueDepth[t.Name]), float64(t.MaxConcurrency), !saturated(t, telemetry)) {
+			break
 		}
-		if req.MaxLatencyMS > 0 && latencyMS > req.MaxLatencyMS {
-			return Decision{
-				Tier:            Tier0,
-				Reason:          "latency_slo_violation",
-				EstimatedCost:   e.tiers[Tier0].BaseCostCents,
-				EstimatedLatency: e.tiers[Tier0].TimeoutMS,
-				ConfidenceThreshold: confThreshold,
-			}
+		if req.BudgetExhausted || EstimateCost(next, req) > budget || saturated(next, telemetry) {
+			break
 		}
-
-		if telemetry.ErrorRate[Tier1] > 0.1 {
-			return Decision{
-				Tier:            Tier0,
-				Reason:          "tier1_high_error_rate",
-				EstimatedCost:   e.tiers[Tier0].BaseCostCents,
-				EstimatedLatency: e.tiers[Tier0].TimeoutMS,
-				ConfidenceThreshold: confThreshold,
-			}
+		if latencyMS := expectedLatency(next, req, telemetry); (maxLatencyMS > 0 && latencyMS > maxLatencyMS) || !fitsDeadline(req, latencyMS) {
+			break
 		}
+		entry = e.entryAt(cascade, i+1, fmt.Sprintf("entry_%s_saturated", t.Name), req, telemetry, x)
+	}
+	return entry
+}
 
-		return Decision{
-			Tier:            Tier1,
-			Reason:          "escalated_low_confidence",
-			EstimatedCost:   e.tiers[Tier1].BaseCostCents,
-			EstimatedLatency: latencyMS,
-			ConfidenceThreshold: e.tiers[Tier1].DefaultConfThreshold,
-		}
+// enterHint starts at the request's EntryHint when it is above the entry
+// picked so far and the request can afford it within its latency SLO and
+// deadline.
+func (e *Engine) enterHint(entry Decision, cascade []TierConfig, req Request, telemetry Telemetry, x *Explanation) Decision {
+	i, j := indexOf(cascade, entry.Tier), indexOf(cascade, req.EntryHint)
+	if !x.check("hint_above_entry", req.EntryHint, float64(j), float64(i), j > i) {
+		return entry
+	}
+	if !x.check("monthly_budget_available", "", boolValue(!req.BudgetExhausted), 1, !req.BudgetExhausted) {
+		return entry
+	}
+	t := cascade[j]
+	cost := EstimateCost(t, req)
+	if !x.check("budget_covers_tier", t.Name, EffectiveBudget(req), cost, cost <= EffectiveBudget(req)) {
+		return entry
 	}
+	if !x.check("tier_not_saturated", t.Name, float64(telemetry.QueueDepth[t.Name]), float64(t.MaxConcurrency), !saturated(t, telemetry)) {
+		return entry
+	}
+	latencyMS := expectedLatency(t, req, telemetry)
+	if maxLatencyMS := maxLatency(req); maxLatencyMS > 0 && !x.check("latency_within_slo", t.Name, float64(latencyMS), float64(maxLatencyMS), latencyMS <= maxLatencyMS) {
+		return entry
+	}
+	if left, ok := remainingMS(req); ok && !x.check("deadline_allows", t.Name, float64(latencyMS), float64(left), latencyMS <= left) {
+		return entry
+	}
+	return e.entryAt(cascade, j, "prerouted", req, telemetry, x)
+}
 
-	if budget >= e.tiers[Tier2].BaseCostCents && e.tiers[Tier2].Enabled {
-		if telemetry.ErrorRate[Tier2] > 0.15 {
-			return Decision{
-				Tier:            Tier1,
-				Reason:          "tier2_high_error_rate",
-				EstimatedCost:   e.tiers[Tier1].BaseCostCents,
-				EstimatedLatency: e.tiers[Tier1].TimeoutMS,
-				ConfidenceThreshold: e.tiers[Tier1].DefaultConfThreshold,
-			}
-		}
+// Decide evaluates the confidence returned by the entry tier.
+func (e *Engine) Decide(req Request, telemetry Telemetry, confidence float64) Decision {
+	first, ok := e.First(req.Policy)
+	if !ok {
+		return Decision{Reason: "no_tiers_enabled"}
+	}
+	return e.DecideAt(first.Name, req, telemetry, confidence)
+}
 
-		latencyMS := e.tiers[Tier2].TimeoutMS
-		if telemetry.P99LatencyMS[Tier2] > 0 {
-			latencyMS = telemetry.P99LatencyMS[Tier2]
-		}
+// DecideAt evaluates the confidence returned by current and either keeps the
+// result or escalates to a more expensive tier, using the strategy selected
+// by the request's policy.
+func (e *Engine) DecideAt(current Tier, req Request, telemetry Telemetry, confidence float64) Decision {
+	x := newExplanation(current, req, telemetry, confidence)
+	var d Decision
+	switch req.Policy.strategy() {
+	case StrategyBandit:
+		d = e.decideBandit(current, req, x)
+	case StrategyUtility:
+		d = e.decideUtility(current, req, telemetry, confidence, x)
+	default:
+		d = e.decideThreshold(current, req, telemetry, confidence, x)
+	}
+	d.Strategy = req.Policy.strategy()
+	if d.Propensity == 0 {
+		d.Propensity = 1
+	}
+	x.Decision, x.Reason = d.Tier, d.Reason
+	d.Explanation = x
+	d.ConfigVersion = e.version
+	return d
+}
+
+func (e *Engine) decideThreshold(current Tier, req Request, telemetry Telemetry, confidence float64, x *Explanation) Decision {
+	cascade := e.Cascade(req.Policy)
+	i := indexOf(cascade, current)
+	if !x.check("tier_allowed", current, 0, 0, i >= 0) {
+		return Decision{Tier: current, Reason: "tier_not_allowed"}
+	}
+	cur := cascade[i]
+	policy := req.Policy
+	if policy == nil {
+		policy = &Policy{}
+	}
+
+	budget := EffectiveBudget(req)
+	maxLatencyMS := maxLatency(req)
+	confThreshold := confidenceThreshold(cur, i, req)
 
+	stay := func(reason string) Decision {
 		return Decision{
-			Tier:            Tier2,
-			Reason:          "high_budget",
-			EstimatedCost:   e.tiers[Tier2].BaseCostCents,
-			EstimatedLatency: latencyMS,
-			ConfidenceThreshold: e.tiers[Tier2].DefaultConfThreshold,
+			Tier:                cur.Name,
+			Reason:              reason,
+			EstimatedCost:       EstimateCost(cur, req),
+			EstimatedLatency:    estimateLatency(cur, req),
+			ConfidenceThreshold: confThreshold,
 		}
 	}
 
+	if x.check("confidence_met", cur.Name, confidence, confThreshold, confidence >= confThreshold) {
+		return stay("confidence_met")
+	}
+
+	if !x.check("monthly_budget_available", "", boolValue(!req.BudgetExhausted), 1, !req.BudgetExhausted) {
+		return stay("monthly_budget_exhausted")
+	}
+
+	if !x.check("has_higher_tier", cur.Name, float64(i+1), float64(len(cascade)), i+1 < len(cascade)) {
+		return stay("highest_tier")
+	}
+	next := cascade[i+1]
+
+	if n := escalations(cascade, i, req); policy.MaxEscalations != nil && !x.check("escalations_left", next.Name, float64(n), float64(*policy.MaxEscalations), n < *policy.MaxEscalations) {
+		return stay("max_escalations_reached")
+	}
+
+	nextCost := EstimateCost(next, req)
+	total := spent(cascade, i, req) + nextCost
+	if !x.check("budget_covers_next", next.Name, budget, total, budget >= total) {
+		return stay("budget_too_low")
+	}
+
+	latencyMS := expectedLatency(next, req, telemetry)
+	if maxLatencyMS > 0 && !x.check("latency_within_slo", next.Name, float64(latencyMS), float64(maxLatencyMS), latencyMS <= maxLatencyMS) {
+		return stay("latency_slo_violation")
+	}
+
+	if left, ok := remainingMS(req); ok && !x.check("deadline_allows", next.Name, float64(latencyMS), float64(left), latencyMS <= left) {
+		return stay("deadline_too_close")
+	}
+
+	if next.MaxErrorRate > 0 && !x.check("error_rate_ok", next.Name, telemetry.ErrorRate[next.Name], next.MaxErrorRate, telemetry.ErrorRate[next.Name] <= next.MaxErrorRate) {
+		return stay(fmt.Sprintf("%s_high_error_rate", next.Name))
+	}
+
+	if !x.check("tier_not_saturated", next.Name, float64(telemetry.QueueDepth[next.Name]), float64(next.MaxConcurrency), !saturated(next, telemetry)) {
+		return stay(fmt.Sprintf("%s_saturated", next.Name))
+	}
+
+	if load(next, telemetry) >= busyLoad && x.check("confidence_near_threshold", cur.Name, confidence, confThreshold-busySlack, confidence >= confThreshold-busySlack) {
+		return stay("next_tier_busy")
+	}
+
 	return Decision{
-		Tier:            Tier0,
-		Reason:          "default",
-		EstimatedCost:   e.tiers[Tier0].BaseCostCents,
-		EstimatedLatency: e.tiers[Tier0].TimeoutMS,
-		ConfidenceThreshold: confThreshold,
+		Tier:                next.Name,
+		Reason:              "escalated_low_confidence",
+		EstimatedCost:       nextCost,
+		EstimatedLatency:    latencyMS,
+		ConfidenceThreshold: confidenceThreshold(next, i+1, req),
+	}
+}
+
+// maxLatency is the request's latency SLO, overridden by policy.
+func maxLatency(req Request) int {
+	if req.Policy != nil && req.Policy.LatencySLOMS > 0 {
+		return req.Policy.LatencySLOMS
+	}
+	return req.MaxLatencyMS
+}
+
+// remainingMS is the time left before req's deadline; ok is false when the
+// request has none.
+func remainingMS(req Request) (int, bool) {
+	if req.Deadline.IsZero() {
+		return 0, false
+	}
+	return int(time.Until(req.Deadline).Milliseconds()), true
+}
+
+// fitsDeadline reports whether a call expected to take latencyMS can finish
+// before req's deadline.
+func fitsDeadline(req Request, latencyMS int) bool {
+	left, ok := remainingMS(req)
+	return !ok || latencyMS <= left
+}
+
+// confidenceThreshold is the confidence t's answer must reach to be kept;
+// i is t's position in the request's cascade.
+func confidenceThreshold(t TierConfig, i int, req Request) float64 {
+	if threshold, ok := req.Policy.threshold(t.Name); ok {
+		return threshold
+	}
+	if req.Priority == "premium" && i == 0 {
+		return premiumConfThreshold
+	}
+	return t.DefaultConfThreshold
+}
+
+// expectedLatency prefers the latency predicted for req's input, then the
+// observed P99 and then the configured timeout, the latter two stretched by
+// the size of req's input, and adds the delay of queueing behind t's
+// backlog: each full round of MaxConcurrency calls ahead costs one more
+// service time.
+func expectedLatency(t TierConfig, req Request, telemetry Telemetry) int {
+	service := telemetry.PredictedLatencyMS[t.Name]
+	if service <= 0 {
+		service = t.TimeoutMS
+		if telemetry.P99LatencyMS[t.Name] > 0 {
+			service = telemetry.P99LatencyMS[t.Name]
+		}
+		service += t.sizeLatency(req.features())
 	}
+	return service + int(float64(service)*load(t, telemetry))
+}
+
+// load is t's queue depth as a share of its concurrency.
+func load(t TierConfig, telemetry Telemetry) float64 {
+	if t.MaxConcurrency <= 0 {
+		return 0
+	}
+	return float64(telemetry.QueueDepth[t.Name]) / float64(t.MaxConcurrency)
+}
+
+// saturated reports whether t already has as many calls queued as it can
+// serve at once.
+func saturated(t TierConfig, telemetry Telemetry) bool {
+	return t.MaxConcurrency > 0 && telemetry.QueueDepth[t.Name] >= t.MaxConcurrency
+}
+
+// EffectiveBudget resolves the per-request budget in cents: the request's
+// budget, then its max cost, then the tenant default, capped by policy.
+func EffectiveBudget(req Request) float64 {
+	budget := req.Budget
+	if budget == 0 {
+		budget = req.MaxCostCents
+	}
+	if budget == 0 {
+		budget = req.DefaultBudget
+	}
+	if budget == 0 {
+		budget = defaultBudgetCents
+	}
+	if req.Policy != nil && req.Policy.MaxCostCents > 0 && budget > req.Policy.MaxCostCents {
+		budget = req.Policy.MaxCostCents
+	}
+	return budget
 }
 
 func (e *Engine) Escalate(currentTier Tier, confidence float64, budget float64) (Tier, string) {
-	if currentTier == Tier0 && confidence < 0.75 && budget >= 2.0 {
-		return Tier1, "escalate_to_tier1"
+	cur, ok := e.Config(currentTier)
+	if !ok {
+		return currentTier, "no_escalation"
 	}
-	if currentTier == Tier1 && confidence < 0.85 && budget >= 5.0 {
-		return Tier2, "escalate_to_tier2"
+	next, ok := e.Next(currentTier, nil)
+	if ok && confidence < cur.DefaultConfThreshold && budget >= next.BaseCostCents {
+		return next.Name, "escalate_to_" + string(next.Name)
 	}
 	return currentTier, "no_escalation"
 }
-
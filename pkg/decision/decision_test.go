@@ -1,6 +1,12 @@
 package decision
 
-import "testing"
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
 
 func TestDecisionEngine(t *testing.T) {
 	engine := NewEngine()
@@ -12,27 +18,27 @@ func TestDecisionEngine(t *testing.T) {
 		expected Tier
 	}{
 		{
-			name: "high confidence tier0",
-			req:  Request{Budget: 10.0, Priority: "normal"},
-			conf: 0.80,
+			name:     "high confidence tier0",
+			req:      Request{Budget: 10.0, Priority: "normal"},
+			conf:     0.80,
 			expected: Tier0,
 		},
 		{
-			name: "low confidence escalate",
-			req:  Request{Budget: 10.0, Priority: "normal"},
-			conf: 0.60,
+			name:     "low confidence escalate",
+			req:      Request{Budget: 10.0, Priority: "normal"},
+			conf:     0.60,
 			expected: Tier1,
 		},
 		{
-			name: "budget too low",
-			req:  Request{Budget: 0.3, Priority: "normal"},
-			conf: 0.60,
+			name:     "budget too low",
+			req:      Request{Budget: 0.3, Priority: "normal"},
+			conf:     0.60,
 			expected: Tier0,
 		},
 		{
-			name: "premium user lower threshold",
-			req:  Request{Budget: 10.0, Priority: "premium"},
-			conf: 0.75,
+			name:     "premium user lower threshold",
+			req:      Request{Budget: 10.0, Priority: "premium"},
+			conf:     0.75,
 			expected: Tier0,
 		},
 	}
@@ -47,3 +53,692 @@ func TestDecisionEngine(t *testing.T) {
 	}
 }
 
+func TestDecideAtArbitraryTiers(t *testing.T) {
+	engine := NewEngineWithTiers([]TierConfig{
+		{Name: "tiny", BaseCostCents: 0.1, TimeoutMS: 20, DefaultConfThreshold: 0.6, Enabled: true},
+		{Name: Tier0, BaseCostCents: 0.5, TimeoutMS: 50, DefaultConfThreshold: 0.75, Enabled: true},
+		{Name: Tier1, BaseCostCents: 2.0, TimeoutMS: 200, DefaultConfThreshold: 0.85, Enabled: false},
+		{Name: Tier2, BaseCostCents: 5.0, TimeoutMS: 500, DefaultConfThreshold: 0.95, MaxErrorRate: 0.15, Enabled: true},
+	})
+
+	if first, _ := engine.First(nil); first.Name != "tiny" {
+		t.Fatalf("expected entry tier tiny, got %v", first.Name)
+	}
+	if next, _ := engine.Next(Tier0, nil); next.Name != Tier2 {
+		t.Fatalf("expected disabled tier1 to be skipped, got %v", next.Name)
+	}
+
+	// A policy naming the disabled tier stays valid; its cascade skips it.
+	policy := &Policy{AllowedTiers: []Tier{Tier0, Tier1, Tier2}, ConfThresholds: map[Tier]float64{Tier1: 0.8}}
+	if err := policy.Validate(engine.ConfiguredTierNames()); err != nil {
+		t.Fatalf("expected a policy naming disabled tier1 to validate, got %v", err)
+	}
+	if cascade := engine.Cascade(policy); len(cascade) != 2 || cascade[0].Name != Tier0 || cascade[1].Name != Tier2 {
+		t.Fatalf("expected the policy's cascade to skip tier1, got %v", cascade)
+	}
+	if d := engine.DecideAt(Tier0, Request{Budget: 10.0, Policy: policy}, Telemetry{}, 0.5); d.Tier != Tier2 {
+		t.Fatalf("expected the policy to escalate past disabled tier1, got %v", d.Tier)
+	}
+	if err := policy.Validate([]Tier{Tier0, Tier2}); err == nil {
+		t.Fatal("expected a policy naming an unconfigured tier to be rejected")
+	}
+
+	tests := []struct {
+		name     string
+		current  Tier
+		req      Request
+		tel      Telemetry
+		conf     float64
+		expected Tier
+		reason   string
+	}{
+		{"entry escalates", "tiny", Request{Budget: 10.0}, Telemetry{}, 0.5, Tier0, "escalated_low_confidence"},
+		{"skip disabled tier", Tier0, Request{Budget: 10.0}, Telemetry{}, 0.5, Tier2, "escalated_low_confidence"},
+		{"budget stops cascade", Tier0, Request{Budget: 3.0}, Telemetry{}, 0.5, Tier0, "budget_too_low"},
+		{"error rate stops cascade", Tier0, Request{Budget: 10.0}, Telemetry{ErrorRate: map[Tier]float64{Tier2: 0.2}}, 0.5, Tier0, "tier2_high_error_rate"},
+		{"highest tier", Tier2, Request{Budget: 10.0}, Telemetry{}, 0.5, Tier2, "highest_tier"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := engine.DecideAt(tt.current, tt.req, tt.tel, tt.conf)
+			if decision.Tier != tt.expected || decision.Reason != tt.reason {
+				t.Errorf("expected %v/%s, got %v/%s", tt.expected, tt.reason, decision.Tier, decision.Reason)
+			}
+		})
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	engine := NewEngine()
+
+	if _, err := ParsePolicy([]byte(`{"allowed_tiers": ["tier0"], "unknown": 1}`)); err == nil {
+		t.Error("expected error for unknown field")
+	}
+
+	invalid := []string{
+		`{"allowed_tiers": []}`,
+		`{"allowed_tiers": ["tier9"]}`,
+		`{"conf_thresholds": {"tier0": 1.5}}`,
+		`{"max_escalations": -1}`,
+	}
+	for _, data := range invalid {
+		p, err := ParsePolicy([]byte(data))
+		if err != nil {
+			t.Fatalf("unexpected parse error for %s: %v", data, err)
+		}
+		if err := p.Validate(engine.TierNames()); err == nil {
+			t.Errorf("expected validation error for %s", data)
+		}
+	}
+
+	zero := 0
+	tests := []struct {
+		name     string
+		policy   *Policy
+		conf     float64
+		expected Tier
+		reason   string
+	}{
+		{"threshold override", &Policy{ConfThresholds: map[Tier]float64{Tier0: 0.5}}, 0.6, Tier0, "confidence_met"},
+		{"allowed tiers skip tier1", &Policy{AllowedTiers: []Tier{Tier0, Tier2}}, 0.6, Tier2, "escalated_low_confidence"},
+		{"max cost caps budget", &Policy{MaxCostCents: 1.0}, 0.6, Tier0, "budget_too_low"},
+		{"no escalations", &Policy{MaxEscalations: &zero}, 0.6, Tier0, "max_escalations_reached"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := engine.Decide(Request{Budget: 10.0, Policy: tt.policy}, Telemetry{}, tt.conf)
+			if decision.Tier != tt.expected || decision.Reason != tt.reason {
+				t.Errorf("expected %v/%s, got %v/%s", tt.expected, tt.reason, decision.Tier, decision.Reason)
+			}
+		})
+	}
+}
+
+func TestBudget(t *testing.T) {
+	engine := NewEngine()
+
+	budgets := []struct {
+		req      Request
+		expected float64
+	}{
+		{Request{Budget: 3.0, MaxCostCents: 7.0, DefaultBudget: 5.0}, 3.0},
+		{Request{MaxCostCents: 7.0, DefaultBudget: 5.0}, 7.0},
+		{Request{DefaultBudget: 5.0}, 5.0},
+		{Request{}, 10.0},
+		{Request{DefaultBudget: 5.0, Policy: &Policy{MaxCostCents: 1.0}}, 1.0},
+	}
+	for _, b := range budgets {
+		if got := EffectiveBudget(b.req); got != b.expected {
+			t.Errorf("expected budget %v, got %v", b.expected, got)
+		}
+	}
+
+	decision := engine.Decide(Request{DefaultBudget: 1.0}, Telemetry{}, 0.6)
+	if decision.Tier != Tier0 || decision.Reason != "budget_too_low" {
+		t.Errorf("expected tenant default budget to block escalation, got %v/%s", decision.Tier, decision.Reason)
+	}
+
+	decision = engine.Decide(Request{Budget: 10.0, BudgetExhausted: true}, Telemetry{}, 0.6)
+	if decision.Tier != Tier0 || decision.Reason != "monthly_budget_exhausted" {
+		t.Errorf("expected exhausted budget to pin tier0, got %v/%s", decision.Tier, decision.Reason)
+	}
+}
+
+func TestAttemptCost(t *testing.T) {
+	tiers := DefaultTiers()
+	tiers[1].BillFailedAttempts = true
+
+	attempts := []Attempt{
+		{Tier: Tier0, Outcome: OutcomeOK, CostCents: AttemptCost(tiers[0], Request{}, OutcomeOK)},
+		{Tier: Tier1, Outcome: OutcomeError, CostCents: AttemptCost(tiers[1], Request{}, OutcomeError)},
+		{Tier: Tier2, Outcome: OutcomeCircuitOpen, CostCents: AttemptCost(tiers[2], Request{}, OutcomeCircuitOpen)},
+		{Tier: Tier2, Outcome: OutcomeError, CostCents: AttemptCost(tiers[2], Request{}, OutcomeError)},
+	}
+
+	if total := TotalCost(attempts); total != 2.5 {
+		t.Errorf("expected total cost 2.5, got %v", total)
+	}
+
+	if cost := AttemptCost(tiers[0], Request{}, OutcomeCanceled); cost != 0.5 {
+		t.Errorf("expected a canceled hedge to be billed 0.5, got %v", cost)
+	}
+}
+
+func TestUtilityStrategy(t *testing.T) {
+	engine := NewEngine()
+	weight := func(w float64) *float64 { return &w }
+
+	tests := []struct {
+		name     string
+		req      Request
+		conf     float64
+		expected Tier
+	}{
+		{"low confidence escalates", Request{Budget: 10.0, Policy: &Policy{Strategy: StrategyUtility}}, 0.60, Tier1},
+		{"high confidence stays", Request{Budget: 10.0, Policy: &Policy{Strategy: StrategyUtility}}, 0.85, Tier0},
+		{"quality only jumps to best tier", Request{Budget: 10.0, Policy: &Policy{Strategy: StrategyUtility, CostWeight: weight(0)}}, 0.60, Tier2},
+		{"cost only never escalates", Request{Budget: 10.0, Policy: &Policy{Strategy: StrategyUtility, CostWeight: weight(1)}}, 0.10, Tier0},
+		{"unaffordable tiers skipped", Request{Budget: 1.0, Policy: &Policy{Strategy: StrategyUtility}}, 0.10, Tier0},
+		{"latency slo excludes slow tiers", Request{Budget: 10.0, MaxLatencyMS: 250, Policy: &Policy{Strategy: StrategyUtility, CostWeight: weight(0)}}, 0.60, Tier1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := engine.Decide(tt.req, Telemetry{}, tt.conf)
+			if decision.Tier != tt.expected {
+				t.Errorf("expected tier %v, got %v (%s)", tt.expected, decision.Tier, decision.Reason)
+			}
+			if decision.Strategy != StrategyUtility {
+				t.Errorf("expected utility strategy, got %s", decision.Strategy)
+			}
+		})
+	}
+}
+
+func TestBanditStrategy(t *testing.T) {
+	epsilon := func(e float64) *float64 { return &e }
+
+	for _, algorithm := range []string{BanditEpsilonGreedy, BanditThompson} {
+		t.Run(algorithm, func(t *testing.T) {
+			engine := NewEngine()
+			engine.UseBandit(NewBandit(1))
+			req := Request{TenantID: "acme", Input: "short", Budget: 10.0, Policy: &Policy{Strategy: StrategyBandit, BanditAlgorithm: algorithm, Epsilon: epsilon(0.1)}}
+			ctx := BanditContext(req)
+
+			// tier1 is the only arm that pays off in this context.
+			picks := make(map[Tier]int)
+			for i := 0; i < 500; i++ {
+				d := engine.Entry(req, Telemetry{})
+				if d.Propensity <= 0 || d.Propensity > 1 {
+					t.Fatalf("propensity %v out of range", d.Propensity)
+				}
+				if d.Context != ctx {
+					t.Fatalf("expected context %s, got %s", ctx, d.Context)
+				}
+				reward := 0.0
+				if d.Tier == Tier1 {
+					reward = 1
+				}
+				engine.Bandit().Update(d.Context, d.Tier, reward)
+				if i >= 400 {
+					picks[d.Tier]++
+				}
+			}
+			if picks[Tier1] < 80 {
+				t.Errorf("expected bandit to settle on tier1, got %v", picks)
+			}
+
+			if d := engine.DecideAt(Tier0, req, Telemetry{}, 0.1); d.Tier != Tier0 || d.Reason != "bandit_arm" {
+				t.Errorf("expected bandit arm to be kept, got %s (%s)", d.Tier, d.Reason)
+			}
+		})
+	}
+
+	engine := NewEngine()
+	req := Request{Budget: 1.0, MaxLatencyMS: 100, Policy: &Policy{Strategy: StrategyBandit}}
+	if d := engine.Entry(req, Telemetry{}); d.Tier != Tier0 || d.Propensity != 1 {
+		t.Errorf("expected only affordable arm tier0 with propensity 1, got %s (%v)", d.Tier, d.Propensity)
+	}
+	if d := engine.Entry(Request{}, Telemetry{}); d.Tier != Tier0 || d.Propensity != 1 || d.Reason != "entry" {
+		t.Errorf("expected threshold entry at tier0, got %+v", d)
+	}
+}
+
+func TestBanditCorrect(t *testing.T) {
+	b := NewBandit(1)
+	b.Update("ctx", Tier0, 0.8)
+	b.Correct("ctx", Tier0, 0.8, 0)
+
+	arm := b.Snapshot()["ctx"][Tier0]
+	if arm.Pulls != 1 || arm.RewardSum > 1e-9 {
+		t.Errorf("expected one pull with zero reward, got %+v", arm)
+	}
+	if arm.Alpha > 1+1e-9 || arm.Beta < 2-1e-9 {
+		t.Errorf("expected posterior Beta(1, 2), got %+v", arm)
+	}
+}
+
+func TestRewardFromLoggedInput(t *testing.T) {
+	tiers := DefaultTiers()
+	tiers[2].Pricing.CostPerKBCents = 4.0
+	engine := NewEngineWithTiers(tiers)
+	req := Request{Input: strings.Repeat("x", 4096)}
+
+	served := engine.Reward(req, 0.9, 2.0)
+	if replayed := engine.RewardFor(req.Policy, Features(req.Input), 0.9, 2.0); math.Abs(replayed-served) > 1e-9 {
+		t.Errorf("expected the logged input to reproduce the served reward %.4f, got %.4f", served, replayed)
+	}
+	if blind := engine.RewardFor(req.Policy, InputFeatures{}, 0.9, 2.0); math.Abs(blind-served) < 1e-9 {
+		t.Error("expected the reward to depend on the input size")
+	}
+}
+
+func TestExplanation(t *testing.T) {
+	engine := NewEngine()
+	req := Request{Budget: 1.0, Priority: "normal"}
+
+	d := engine.Decide(req, Telemetry{}, 0.5)
+	if d.Reason != "budget_too_low" {
+		t.Fatalf("expected budget_too_low, got %s", d.Reason)
+	}
+	x := d.Explanation
+	if x == nil || x.EffectiveBudget != 1.0 || x.Decision != Tier0 || x.Reason != d.Reason {
+		t.Fatalf("unexpected explanation %+v", x)
+	}
+
+	var failed []string
+	for _, c := range x.Checks {
+		if !c.Passed {
+			failed = append(failed, c.Rule)
+		}
+	}
+	// confidence_met fails (so the engine looks at escalating) and the
+	// budget check is the one that stops it.
+	if len(failed) != 2 || failed[0] != "confidence_met" || failed[1] != "budget_covers_next" {
+		t.Errorf("expected confidence_met and budget_covers_next to fail, got %v", failed)
+	}
+	last := x.Checks[len(x.Checks)-1]
+	// The limit is what tier0 and tier1 cost together.
+	if last.Tier != Tier1 || last.Value != 1.0 || last.Limit != 2.5 {
+		t.Errorf("unexpected budget check %+v", last)
+	}
+}
+
+func TestBudgetCoversCascade(t *testing.T) {
+	engine := NewEngine()
+
+	// tier1 alone (2.0) fits the budget, but not on top of tier0 (0.5).
+	d := engine.DecideAt(Tier0, Request{Budget: 2.2}, Telemetry{}, 0.5)
+	if d.Tier != Tier0 || d.Reason != "budget_too_low" {
+		t.Errorf("expected tier0 + tier1 to exceed the budget, got %s (%s)", d.Tier, d.Reason)
+	}
+	if d = engine.DecideAt(Tier0, Request{Budget: 2.5}, Telemetry{}, 0.5); d.Tier != Tier1 {
+		t.Errorf("expected tier0 + tier1 to fit the budget, got %s (%s)", d.Tier, d.Reason)
+	}
+
+	// Spend counts from the entry tier, not the bottom of the cascade.
+	req := Request{Budget: 7.0, EntryTier: Tier1}
+	if d = engine.DecideAt(Tier1, req, Telemetry{}, 0.5); d.Tier != Tier2 {
+		t.Errorf("expected tier1 + tier2 to fit the budget, got %s (%s)", d.Tier, d.Reason)
+	}
+	req.EntryTier = ""
+	if d = engine.DecideAt(Tier1, req, Telemetry{}, 0.5); d.Tier != Tier1 || d.Reason != "budget_too_low" {
+		t.Errorf("expected the whole cascade to exceed the budget, got %s (%s)", d.Tier, d.Reason)
+	}
+}
+
+func TestQueueDepth(t *testing.T) {
+	engine := NewEngine()
+	req := Request{Budget: 10.0, Priority: "normal"}
+	queued := func(depths map[Tier]int) Telemetry { return Telemetry{QueueDepth: depths} }
+
+	tests := []struct {
+		name      string
+		req       Request
+		telemetry Telemetry
+		conf      float64
+		expected  Tier
+		reason    string
+	}{
+		{"idle tier1 takes the escalation", req, queued(nil), 0.60, Tier1, "escalated_low_confidence"},
+		{"saturated tier1 is avoided", req, queued(map[Tier]int{Tier1: 50}), 0.60, Tier0, "tier1_saturated"},
+		{"busy tier1 waits for a near miss", req, queued(map[Tier]int{Tier1: 45}), 0.72, Tier0, "next_tier_busy"},
+		{"busy tier1 still takes a clear miss", req, queued(map[Tier]int{Tier1: 45}), 0.60, Tier1, "escalated_low_confidence"},
+		// 200ms service time plus half a round of queueing exceeds 250ms.
+		{"queueing delay counts against the slo", Request{Budget: 10.0, MaxLatencyMS: 250}, queued(map[Tier]int{Tier1: 25}), 0.60, Tier0, "latency_slo_violation"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := engine.Decide(tt.req, tt.telemetry, tt.conf)
+			if d.Tier != tt.expected || d.Reason != tt.reason {
+				t.Errorf("expected %s (%s), got %s (%s)", tt.expected, tt.reason, d.Tier, d.Reason)
+			}
+		})
+	}
+
+	if d := engine.Entry(req, queued(map[Tier]int{Tier0: 100})); d.Tier != Tier1 || d.Reason != "entry_tier0_saturated" {
+		t.Errorf("expected entry to skip saturated tier0, got %s (%s)", d.Tier, d.Reason)
+	}
+	if d := engine.Entry(Request{Budget: 1.0}, queued(map[Tier]int{Tier0: 100})); d.Tier != Tier0 {
+		t.Errorf("expected entry to stay on tier0 when tier1 is unaffordable, got %s", d.Tier)
+	}
+}
+
+func TestHedge(t *testing.T) {
+	engine := NewEngine()
+	hedging := &Policy{Hedge: true}
+	fast := Telemetry{P95LatencyMS: map[Tier]int{Tier0: 20}}
+
+	tests := []struct {
+		name      string
+		req       Request
+		telemetry Telemetry
+		hedge     bool
+	}{
+		// Serially tier0 (50ms) then tier1 (200ms) misses 240ms; hedging
+		// after tier0's 20ms P95 makes it.
+		{"tight slo hedges", Request{Budget: 10.0, MaxLatencyMS: 240, Policy: hedging}, fast, true},
+		{"policy must opt in", Request{Budget: 10.0, MaxLatencyMS: 240}, fast, false},
+		{"loose slo runs serially", Request{Budget: 10.0, MaxLatencyMS: 1000, Policy: hedging}, fast, false},
+		{"no slo runs serially", Request{Budget: 10.0, Policy: hedging}, fast, false},
+		{"hedge must still fit the slo", Request{Budget: 10.0, MaxLatencyMS: 240, Policy: hedging}, Telemetry{}, false},
+		{"budget must cover both calls", Request{Budget: 2.0, MaxLatencyMS: 240, Policy: hedging}, fast, false},
+		{"saturated tier is not hedged to", Request{Budget: 10.0, MaxLatencyMS: 240, Policy: hedging}, Telemetry{P95LatencyMS: fast.P95LatencyMS, QueueDepth: map[Tier]int{Tier1: 50}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, ok := engine.Hedge(Tier0, tt.req, tt.telemetry)
+			if ok != tt.hedge {
+				t.Fatalf("expected hedge %v, got %v", tt.hedge, ok)
+			}
+			if ok && (plan.Next.Name != Tier1 || plan.DelayMS != 20) {
+				t.Errorf("expected to hedge to tier1 after 20ms, got %+v", plan)
+			}
+		})
+	}
+
+	if _, ok := engine.Hedge(Tier2, Request{Budget: 10.0, MaxLatencyMS: 240, Policy: hedging}, fast); ok {
+		t.Error("expected no hedge from the top tier")
+	}
+}
+
+func TestParallel(t *testing.T) {
+	engine := NewEngine()
+	parallel := &Policy{CascadeMode: CascadeParallel}
+	names := func(tiers []TierConfig) []Tier {
+		out := make([]Tier, len(tiers))
+		for i, t := range tiers {
+			out[i] = t.Name
+		}
+		return out
+	}
+
+	tests := []struct {
+		name      string
+		req       Request
+		telemetry Telemetry
+		expected  []Tier
+	}{
+		{"budget covers every tier", Request{Budget: 10.0, Policy: parallel}, Telemetry{}, []Tier{Tier0, Tier1, Tier2}},
+		{"budget covers two tiers", Request{Budget: 3.0, Policy: parallel}, Telemetry{}, []Tier{Tier0, Tier1}},
+		{"slow tiers are left out", Request{Budget: 10.0, MaxLatencyMS: 300, Policy: parallel}, Telemetry{}, []Tier{Tier0, Tier1}},
+		{"saturated tiers are skipped", Request{Budget: 10.0, Policy: parallel}, Telemetry{QueueDepth: map[Tier]int{Tier1: 50}}, []Tier{Tier0, Tier2}},
+		{"serial mode", Request{Budget: 10.0}, Telemetry{}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := names(engine.Parallel(Tier0, tt.req, tt.telemetry))
+			if len(got) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, got)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Fatalf("expected %v, got %v", tt.expected, got)
+				}
+			}
+		})
+	}
+
+	bad := &Policy{CascadeMode: CascadeParallel, Strategy: StrategyBandit}
+	if err := bad.Validate(engine.TierNames()); err == nil {
+		t.Error("expected parallel bandit policy to be rejected")
+	}
+}
+
+func TestEnsemble(t *testing.T) {
+	agree := Ensemble([]Vote{
+		{Tier: Tier0, Result: "cat", Confidence: 0.7},
+		{Tier: Tier1, Result: "Cat ", Confidence: 0.8},
+	})
+	if agree.Result != "Cat " || agree.Agreement != 1 || len(agree.Tiers) != 2 || len(agree.Dissent) != 0 {
+		t.Errorf("unexpected consensus %+v", agree)
+	}
+	if math.Abs(agree.Confidence-0.94) > 1e-9 {
+		t.Errorf("expected agreement to boost confidence to 0.94, got %v", agree.Confidence)
+	}
+
+	disagree := Ensemble([]Vote{
+		{Tier: Tier0, Result: "cat", Confidence: 0.7},
+		{Tier: Tier1, Result: "dog", Confidence: 0.8},
+	})
+	if disagree.Result != "dog" || disagree.Agreement != 0.5 || len(disagree.Dissent) != 1 || disagree.Dissent[0] != Tier0 {
+		t.Errorf("unexpected consensus %+v", disagree)
+	}
+	if math.Abs(disagree.Confidence-0.24) > 1e-9 {
+		t.Errorf("expected disagreement to lower confidence to 0.24, got %v", disagree.Confidence)
+	}
+
+	tie := Ensemble([]Vote{
+		{Tier: Tier0, Result: "cat", Confidence: 0.8},
+		{Tier: Tier1, Result: "dog", Confidence: 0.8},
+	})
+	if tie.Result != "dog" {
+		t.Errorf("expected the latest tier to win a tie, got %q", tie.Result)
+	}
+
+	engine := NewEngine()
+	req := Request{Budget: 10.0, Policy: &Policy{Strategy: StrategyEnsemble}}
+	dec, c := engine.DecideEnsemble(Tier1, req, Telemetry{}, []Vote{
+		{Tier: Tier0, Result: "cat", Confidence: 0.7},
+		{Tier: Tier1, Result: "cat", Confidence: 0.8},
+	})
+	if dec.Tier != Tier1 || dec.Reason != "confidence_met" || dec.Strategy != StrategyEnsemble || c.Confidence < 0.9 {
+		t.Errorf("expected agreeing tiers to stop early, got %s (%s) at %v", dec.Tier, dec.Reason, c.Confidence)
+	}
+	dec, _ = engine.DecideEnsemble(Tier1, req, Telemetry{}, []Vote{
+		{Tier: Tier0, Result: "cat", Confidence: 0.7},
+		{Tier: Tier1, Result: "dog", Confidence: 0.9},
+	})
+	if dec.Tier != Tier2 || dec.Reason != "escalated_disagreement" || dec.Explanation.Reason != dec.Reason {
+		t.Errorf("expected disagreement to escalate, got %s (%s)", dec.Tier, dec.Reason)
+	}
+}
+
+func TestPricing(t *testing.T) {
+	f := Features("a fairly short input")
+	if f.Chars != 20 || f.Tokens != 5 || f.Bytes != 22 || f.Items != 0 {
+		t.Errorf("unexpected features for text: %+v", f)
+	}
+	f = Features([]interface{}{"abcd", "efgh", 42.0})
+	if f.Items != 3 || f.Chars != 10 || f.Tokens != 3 {
+		t.Errorf("unexpected features for list: %+v", f)
+	}
+	if f := Features(nil); f != (InputFeatures{}) {
+		t.Errorf("expected no features for nil input, got %+v", f)
+	}
+
+	tier := TierConfig{Name: Tier1, BaseCostCents: 2.0, TimeoutMS: 200, Pricing: Pricing{CostPer1KTokensCents: 1.0, CostPerItemCents: 0.5, LatencyPer1KTokensMS: 100}}
+	if cost := tier.Cost(InputFeatures{Tokens: 2000, Items: 2}); math.Abs(cost-5.0) > 1e-9 {
+		t.Errorf("expected cost 5.0, got %v", cost)
+	}
+	if flat := (TierConfig{BaseCostCents: 2.0}).Cost(InputFeatures{Tokens: 2000, Bytes: 8000, Items: 2}); flat != 2.0 {
+		t.Errorf("expected zero coefficients to keep the flat cost, got %v", flat)
+	}
+
+	tiers := DefaultTiers()
+	tiers[1].Pricing = Pricing{CostPer1KTokensCents: 1.0, LatencyPer1KTokensMS: 100}
+	engine := NewEngineWithTiers(tiers)
+	long := strings.Repeat("x", 8000)
+
+	short := Request{Input: "short", Budget: 3.0}
+	if d := engine.DecideAt(Tier0, short, Telemetry{}, 0.5); d.Tier != Tier1 || d.EstimatedCost > 2.01 {
+		t.Errorf("expected short input to escalate at about base cost, got %s at %v", d.Tier, d.EstimatedCost)
+	}
+	big := Request{Input: long, Budget: 3.0}
+	if d := engine.DecideAt(Tier0, big, Telemetry{}, 0.5); d.Reason != "budget_too_low" {
+		t.Errorf("expected long input to price tier1 over budget, got %s (%s)", d.Tier, d.Reason)
+	}
+	if cost := AttemptCost(tiers[1], big, OutcomeOK); math.Abs(cost-4.0) > 1e-9 {
+		t.Errorf("expected attempt to be billed 4.0, got %v", cost)
+	}
+	slo := Request{Input: long, Budget: 10.0, MaxLatencyMS: 300}
+	if d := engine.DecideAt(Tier0, slo, Telemetry{}, 0.5); d.Reason != "latency_slo_violation" {
+		t.Errorf("expected long input to stretch tier1 past the SLO, got %s (%s)", d.Tier, d.Reason)
+	}
+}
+
+func TestPredictedLatency(t *testing.T) {
+	engine := NewEngine()
+	req := Request{Budget: 10.0, MaxLatencyMS: 300}
+
+	// tier1's global P99 breaks the SLO, but this request is predicted fast.
+	telemetry := Telemetry{
+		P99LatencyMS:       map[Tier]int{Tier1: 400},
+		PredictedLatencyMS: map[Tier]int{Tier1: 150},
+	}
+	if d := engine.DecideAt(Tier0, req, telemetry, 0.5); d.Tier != Tier1 || d.EstimatedLatency != 150 {
+		t.Errorf("expected escalation at the predicted 150ms, got %s (%s) at %dms", d.Tier, d.Reason, d.EstimatedLatency)
+	}
+
+	// And the other way round: a slow prediction wins over a fast P99.
+	telemetry = Telemetry{
+		P99LatencyMS:       map[Tier]int{Tier1: 100},
+		PredictedLatencyMS: map[Tier]int{Tier1: 350},
+	}
+	if d := engine.DecideAt(Tier0, req, telemetry, 0.5); d.Reason != "latency_slo_violation" {
+		t.Errorf("expected the predicted latency to break the SLO, got %s (%s)", d.Tier, d.Reason)
+	}
+}
+
+func TestDeadline(t *testing.T) {
+	engine := NewEngine()
+
+	soon := Request{Budget: 10.0, Deadline: time.Now().Add(100 * time.Millisecond)}
+	if d := engine.DecideAt(Tier0, soon, Telemetry{}, 0.5); d.Tier != Tier0 || d.Reason != "deadline_too_close" {
+		t.Errorf("expected escalation past the deadline to be skipped, got %s (%s)", d.Tier, d.Reason)
+	}
+	later := Request{Budget: 10.0, Deadline: time.Now().Add(10 * time.Second)}
+	if d := engine.DecideAt(Tier0, later, Telemetry{}, 0.5); d.Tier != Tier1 {
+		t.Errorf("expected escalation within the deadline, got %s (%s)", d.Tier, d.Reason)
+	}
+
+	utility := Request{Budget: 10.0, Deadline: time.Now().Add(300 * time.Millisecond), Policy: &Policy{Strategy: StrategyUtility}}
+	if d := engine.DecideAt(Tier0, utility, Telemetry{}, 0.1); d.Tier == Tier2 {
+		t.Error("expected utility to skip tier2, which cannot finish before the deadline")
+	}
+
+	parallel := Request{Budget: 10.0, Deadline: time.Now().Add(300 * time.Millisecond), Policy: &Policy{CascadeMode: CascadeParallel}}
+	if tiers := engine.Parallel(Tier0, parallel, Telemetry{}); len(tiers) != 2 {
+		t.Errorf("expected the parallel cascade to stop before tier2, got %d tiers", len(tiers))
+	}
+}
+
+func TestEntryHint(t *testing.T) {
+	engine := NewEngine()
+
+	d := engine.Entry(Request{Budget: 10.0, EntryHint: Tier1}, Telemetry{})
+	if d.Tier != Tier1 || d.Reason != "prerouted" {
+		t.Errorf("expected to start at tier1, got %s (%s)", d.Tier, d.Reason)
+	}
+	d = engine.Entry(Request{Budget: 1.0, EntryHint: Tier1}, Telemetry{})
+	if d.Tier != Tier0 {
+		t.Errorf("expected an unaffordable hint to be ignored, got %s (%s)", d.Tier, d.Reason)
+	}
+	d = engine.Entry(Request{Budget: 10.0, EntryHint: Tier1}, Telemetry{QueueDepth: map[Tier]int{Tier1: 50}})
+	if d.Tier != Tier0 {
+		t.Errorf("expected a saturated hint to be ignored, got %s (%s)", d.Tier, d.Reason)
+	}
+	d = engine.Entry(Request{Budget: 10.0, EntryHint: Tier1, Policy: &Policy{AllowedTiers: []Tier{Tier0, Tier2}}}, Telemetry{})
+	if d.Tier != Tier0 {
+		t.Errorf("expected a hint outside the policy to be ignored, got %s (%s)", d.Tier, d.Reason)
+	}
+}
+
+func TestMaxEscalationsFromEntry(t *testing.T) {
+	engine := NewEngine()
+	one := 1
+
+	for _, strategy := range []string{StrategyThreshold, StrategyUtility} {
+		policy := &Policy{MaxEscalations: &one, Strategy: strategy}
+		entered := Request{Budget: 10.0, EntryTier: Tier1, Policy: policy}
+		if d := engine.DecideAt(Tier1, entered, Telemetry{}, 0.1); d.Tier != Tier2 {
+			t.Errorf("%s: expected a request entered at tier1 to have an escalation left, got %s (%s)", strategy, d.Tier, d.Reason)
+		}
+		escalated := Request{Budget: 10.0, EntryTier: Tier0, Policy: policy}
+		if d := engine.DecideAt(Tier1, escalated, Telemetry{}, 0.1); d.Reason != "max_escalations_reached" {
+			t.Errorf("%s: expected a request escalated from tier0 to be out of escalations, got %s (%s)", strategy, d.Tier, d.Reason)
+		}
+	}
+
+	// Serially tier1 (200ms) then tier2 (500ms) misses 600ms; hedging after
+	// tier1's 50ms P95 makes it.
+	hedging := &Policy{MaxEscalations: &one, Hedge: true}
+	fast := Telemetry{P95LatencyMS: map[Tier]int{Tier1: 50}}
+	if _, ok := engine.Hedge(Tier1, Request{Budget: 10.0, MaxLatencyMS: 600, EntryTier: Tier1, Policy: hedging}, fast); !ok {
+		t.Error("expected a request entered at tier1 to be hedged to tier2")
+	}
+	if _, ok := engine.Hedge(Tier1, Request{Budget: 10.0, MaxLatencyMS: 600, EntryTier: Tier0, Policy: hedging}, fast); ok {
+		t.Error("expected no hedge once the escalation is spent")
+	}
+}
+
+func TestLiveEngine(t *testing.T) {
+	live := NewLiveEngine(DefaultTiers())
+	old := live.Load()
+	if d := old.Entry(Request{Budget: 10.0}, Telemetry{}); d.ConfigVersion != 1 {
+		t.Errorf("expected decisions on config version 1, got %d", d.ConfigVersion)
+	}
+
+	bad := DefaultTiers()
+	bad[1].DefaultConfThreshold = 1.5
+	_, err := live.Swap(bad)
+	var cfgErr *ConfigError
+	if !errors.As(err, &cfgErr) || cfgErr.Field != "tiers[1].default_conf_threshold" {
+		t.Errorf("expected a config error on the threshold, got %v", err)
+	}
+	if live.Load() != old {
+		t.Error("expected an invalid config to leave the engine in place")
+	}
+	if e, _ := live.Swap(DefaultTiers()); e != old {
+		t.Error("expected an unchanged config to keep the engine")
+	}
+
+	tiers := DefaultTiers()
+	tiers[0].DefaultConfThreshold = 0.5
+	e, err := live.Swap(tiers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Version() != 2 || live.Load() != e {
+		t.Errorf("expected config version 2 to be live, got %d", e.Version())
+	}
+	if e.Bandit() != old.Bandit() {
+		t.Error("expected the bandit to carry over")
+	}
+	// A decision that loaded the old engine finishes on the old thresholds.
+	if d := old.DecideAt(Tier0, Request{Budget: 10.0}, Telemetry{}, 0.6); d.Tier != Tier1 || d.ConfigVersion != 1 {
+		t.Errorf("expected the old engine to escalate on version 1, got %s on %d", d.Tier, d.ConfigVersion)
+	}
+	if d := e.DecideAt(Tier0, Request{Budget: 10.0}, Telemetry{}, 0.6); d.Tier != Tier0 || d.ConfigVersion != 2 {
+		t.Errorf("expected the new engine to accept on version 2, got %s on %d", d.Tier, d.ConfigVersion)
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	if _, err := ParseConfig([]byte(`{"tiers": [{"name": "tier0", "base_cost_cents": 0.5, "timeout_ms": 50, "default_conf_threshold": 0.8, "enabled": true}]}`)); err != nil {
+		t.Errorf("expected a valid config, got %v", err)
+	}
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"unknown field", `{"tiers": [], "strategy": "utility"}`},
+		{"no tiers", `{"tiers": []}`},
+		{"duplicate tier", `{"tiers": [{"name": "tier0", "timeout_ms": 50, "enabled": true}, {"name": "tier0", "timeout_ms": 50, "enabled": true}]}`},
+		{"no timeout", `{"tiers": [{"name": "tier0", "enabled": true}]}`},
+		{"none enabled", `{"tiers": [{"name": "tier0", "timeout_ms": 50}]}`},
+		{"negative pricing", `{"tiers": [{"name": "tier0", "timeout_ms": 50, "enabled": true, "pricing": {"cost_per_kb_cents": -1}}]}`},
+	}
+	for _, tt := range tests {
+		var cfgErr *ConfigError
+		if _, err := ParseConfig([]byte(tt.data)); !errors.As(err, &cfgErr) {
+			t.Errorf("%s: expected a config error, got %v", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package decision
+
+import "strings"
+
+// Vote is one tier's answer to a request, with its calibrated confidence.
+type Vote struct {
+	Tier       Tier    `json:"tier"`
+	Result     string  `json:"result"`
+	Confidence float64 `json:"confidence"`
+}
+
+// Consensus is the answer the votes of the tiers called so far settle on.
+// Tiers lists the tiers whose answers agree with Result and Agreement is
+// their share of the votes.
+type Consensus struct {
+	Result     string  `json:"result"`
+	Confidence float64 `json:"confidence"`
+	Tiers      []Tier  `json:"tiers"`
+	Dissent    []Tier  `json:"dissent,omitempty"`
+	Agreement  float64 `json:"agreement"`
+}
+
+// Ensemble groups votes by answer and picks the group with the highest
+// combined confidence, preferring the group of the latest (most expensive)
+// tier on ties. Votes are treated as independent evidence: agreeing votes
+// combine as 1-Π(1-c), and the result is discounted by the combined
+// confidence of the dissenting votes, so agreement raises the effective
+// confidence and disagreement lowers it.
+func Ensemble(votes []Vote) Consensus {
+	if len(votes) == 0 {
+		return Consensus{}
+	}
+	groups := make(map[string][]Vote)
+	var order []string
+	for _, v := range votes {
+		key := normalizeResult(v.Result)
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], v)
+	}
+
+	latest := normalizeResult(votes[len(votes)-1].Result)
+	best, bestScore := latest, combine(groups[latest])
+	for _, key := range order {
+		if score := combine(groups[key]); score > bestScore {
+			best, bestScore = key, score
+		}
+	}
+
+	c := Consensus{
+		Result:    groups[best][len(groups[best])-1].Result,
+		Agreement: float64(len(groups[best])) / float64(len(votes)),
+	}
+	var dissent []Vote
+	for _, v := range votes {
+		if normalizeResult(v.Result) == best {
+			c.Tiers = append(c.Tiers, v.Tier)
+		} else {
+			c.Dissent = append(c.Dissent, v.Tier)
+			dissent = append(dissent, v)
+		}
+	}
+	c.Confidence = bestScore * (1 - combine(dissent))
+	return c
+}
+
+// DecideEnsemble decides on the consensus of votes, which end with current's
+// answer. The threshold rules run on the consensus confidence; an
+// escalation caused by tiers disagreeing is reported as such.
+func (e *Engine) DecideEnsemble(current Tier, req Request, telemetry Telemetry, votes []Vote) (Decision, Consensus) {
+	c := Ensemble(votes)
+	d := e.DecideAt(current, req, telemetry, c.Confidence)
+	d.Explanation.check("tiers_agree", current, c.Agreement, 1, len(c.Dissent) == 0)
+	if d.Tier != current && len(c.Dissent) > 0 {
+		d.Reason = "escalated_disagreement"
+		d.Explanation.Reason = d.Reason
+	}
+	return d, c
+}
+
+// combine is the probability that at least one of the votes is right.
+func combine(votes []Vote) float64 {
+	miss := 1.0
+	for _, v := range votes {
+		miss *= 1 - v.Confidence
+	}
+	return 1 - miss
+}
+
+func normalizeResult(result string) string {
+	return strings.ToLower(strings.TrimSpace(result))
+}
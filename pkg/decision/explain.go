@@ -0,0 +1,52 @@
+package decision
+
+// Check is one rule the engine evaluated. Passed reports whether the
+// condition named by Rule held; the first failing check on the escalation
+// path is the one a stay decision's reason names.
+type Check struct {
+	Rule   string  `json:"rule"`
+	Tier   Tier    `json:"tier,omitempty"`
+	Value  float64 `json:"value"`
+	Limit  float64 `json:"limit"`
+	Passed bool    `json:"passed"`
+}
+
+// Explanation records the inputs and rules behind one decision. Tier is the
+// tier whose answer was evaluated; it is empty for the entry decision.
+type Explanation struct {
+	Tier            Tier          `json:"tier,omitempty"`
+	Strategy        string        `json:"strategy"`
+	Confidence      float64       `json:"confidence"`
+	EffectiveBudget float64       `json:"effective_budget_cents"`
+	MaxLatencyMS    int           `json:"max_latency_ms,omitempty"`
+	Input           InputFeatures `json:"input"`
+	Telemetry       Telemetry     `json:"telemetry"`
+	Checks          []Check       `json:"checks"`
+	Decision        Tier          `json:"decision"`
+	Reason          string        `json:"reason"`
+}
+
+func newExplanation(current Tier, req Request, telemetry Telemetry, confidence float64) *Explanation {
+	return &Explanation{
+		Tier:            current,
+		Strategy:        req.Policy.strategy(),
+		Confidence:      confidence,
+		EffectiveBudget: EffectiveBudget(req),
+		MaxLatencyMS:    maxLatency(req),
+		Input:           req.features(),
+		Telemetry:       telemetry,
+	}
+}
+
+// check records a rule and returns whether it passed.
+func (x *Explanation) check(rule string, tier Tier, value, limit float64, passed bool) bool {
+	x.Checks = append(x.Checks, Check{Rule: rule, Tier: tier, Value: value, Limit: limit, Passed: passed})
+	return passed
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
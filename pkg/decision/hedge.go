@@ -0,0 +1,67 @@
+package decision
+
+// HedgePlan says when to start Next alongside the tier already called.
+type HedgePlan struct {
+	Next    TierConfig
+	DelayMS int
+}
+
+// Hedge plans a speculative call to the tier after current for requests
+// whose policy enables hedging and whose latency SLO the serial cascade
+// could miss. The hedge starts once current has run for its observed P95
+// and is only planned when the request can afford both calls, the hedge can
+// finish before the request's deadline and the next tier would be allowed as
+// an escalation target.
+func (e *Engine) Hedge(current Tier, req Request, telemetry Telemetry) (HedgePlan, bool) {
+	if req.Policy == nil || !req.Policy.Hedge || req.Policy.strategy() == StrategyBandit || req.BudgetExhausted {
+		return HedgePlan{}, false
+	}
+	maxLatencyMS := maxLatency(req)
+	if maxLatencyMS <= 0 {
+		return HedgePlan{}, false
+	}
+
+	cascade := e.Cascade(req.Policy)
+	i := indexOf(cascade, current)
+	if i < 0 || i+1 >= len(cascade) {
+		return HedgePlan{}, false
+	}
+	if req.Policy.MaxEscalations != nil && escalations(cascade, i, req) >= *req.Policy.MaxEscalations {
+		return HedgePlan{}, false
+	}
+	cur, next := cascade[i], cascade[i+1]
+
+	if EffectiveBudget(req) < spent(cascade, i, req)+EstimateCost(next, req) {
+		return HedgePlan{}, false
+	}
+	if saturated(next, telemetry) {
+		return HedgePlan{}, false
+	}
+	if next.MaxErrorRate > 0 && telemetry.ErrorRate[next.Name] > next.MaxErrorRate {
+		return HedgePlan{}, false
+	}
+
+	delay := hedgeDelay(cur, telemetry)
+	nextLatency := expectedLatency(next, req, telemetry)
+	// Only hedge when waiting for current before escalating would miss the
+	// SLO but starting next after the delay would not.
+	if expectedLatency(cur, req, telemetry)+nextLatency <= maxLatencyMS || delay+nextLatency > maxLatencyMS {
+		return HedgePlan{}, false
+	}
+	if !fitsDeadline(req, delay+nextLatency) {
+		return HedgePlan{}, false
+	}
+	return HedgePlan{Next: next, DelayMS: delay}, true
+}
+
+// hedgeDelay is how long to wait for t before hedging: its P95, falling back
+// to the P99 and then the timeout.
+func hedgeDelay(t TierConfig, telemetry Telemetry) int {
+	if telemetry.P95LatencyMS[t.Name] > 0 {
+		return telemetry.P95LatencyMS[t.Name]
+	}
+	if telemetry.P99LatencyMS[t.Name] > 0 {
+		return telemetry.P99LatencyMS[t.Name]
+	}
+	return t.TimeoutMS
+}
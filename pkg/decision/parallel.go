@@ -0,0 +1,41 @@
+package decision
+
+// Parallel lists the tiers a parallel cascade calls at once, starting with
+// current: every later tier while the per-request budget covers all of them
+// together and each answers within the latency SLO and the request's
+// deadline on its own. Saturated tiers and tiers over their error-rate
+// cutoff are left out. It returns nil unless the policy selects the
+// parallel mode.
+func (e *Engine) Parallel(current Tier, req Request, telemetry Telemetry) []TierConfig {
+	if req.Policy == nil || req.Policy.CascadeMode != CascadeParallel || req.BudgetExhausted {
+		return nil
+	}
+	cascade := e.Cascade(req.Policy)
+	i := indexOf(cascade, current)
+	if i < 0 {
+		return nil
+	}
+
+	budget := EffectiveBudget(req)
+	maxLatencyMS := maxLatency(req)
+	tiers := []TierConfig{cascade[i]}
+	total := spent(cascade, i, req)
+	for _, t := range cascade[i+1:] {
+		if req.Policy.MaxEscalations != nil && escalations(cascade, i, req)+len(tiers) > *req.Policy.MaxEscalations {
+			break
+		}
+		cost := EstimateCost(t, req)
+		if total+cost > budget {
+			break
+		}
+		if latencyMS := expectedLatency(t, req, telemetry); (maxLatencyMS > 0 && latencyMS > maxLatencyMS) || !fitsDeadline(req, latencyMS) {
+			break
+		}
+		if saturated(t, telemetry) || (t.MaxErrorRate > 0 && telemetry.ErrorRate[t.Name] > t.MaxErrorRate) {
+			continue
+		}
+		tiers = append(tiers, t)
+		total += cost
+	}
+	return tiers
+}
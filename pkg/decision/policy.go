@@ -0,0 +1,198 @@
+package decision
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Policy is the per-tenant document stored in policies.policy_json. Zero
+// values leave the engine defaults in place.
+type Policy struct {
+	AllowedTiers   []Tier           `json:"allowed_tiers,omitempty"`
+	ConfThresholds map[Tier]float64 `json:"conf_thresholds,omitempty"`
+	MaxCostCents   float64          `json:"max_cost_cents,omitempty"`
+	LatencySLOMS   int              `json:"latency_slo_ms,omitempty"`
+	MaxEscalations *int             `json:"max_escalations,omitempty"`
+	// OnBudgetExhausted is "downgrade" (default) or "reject".
+	OnBudgetExhausted string `json:"on_budget_exhausted,omitempty"`
+	// Strategy is "threshold" (default), "utility", "bandit" or "ensemble",
+	// which applies the threshold rules to the consensus of every tier
+	// called so far.
+	Strategy string `json:"strategy,omitempty"`
+	// CostWeight trades quality for cost in the utility strategy: 0 ignores
+	// cost and latency, 1 ignores accuracy. Defaults to 0.5.
+	CostWeight *float64 `json:"cost_weight,omitempty"`
+	// TargetAccuracy is the accuracy the threshold tuner aims for at
+	// minimum cost.
+	TargetAccuracy float64 `json:"target_accuracy,omitempty"`
+	// BanditAlgorithm is "epsilon_greedy" (default) or "thompson".
+	BanditAlgorithm string   `json:"bandit_algorithm,omitempty"`
+	Epsilon         *float64 `json:"epsilon,omitempty"`
+	// Hedge lets requests with a tight latency SLO start the next tier in
+	// parallel when the current one is slow to answer.
+	Hedge bool `json:"hedge,omitempty"`
+	// CascadeMode is "serial" (default) or "parallel", which calls every
+	// tier the budget allows at once and keeps the cheapest answer that
+	// meets its threshold.
+	CascadeMode string `json:"cascade_mode,omitempty"`
+}
+
+const (
+	BudgetExhaustedDowngrade = "downgrade"
+	BudgetExhaustedReject    = "reject"
+)
+
+const (
+	StrategyThreshold = "threshold"
+	StrategyUtility   = "utility"
+	StrategyBandit    = "bandit"
+	StrategyEnsemble  = "ensemble"
+)
+
+const (
+	CascadeSerial   = "serial"
+	CascadeParallel = "parallel"
+)
+
+const defaultCostWeight = 0.5
+
+type PolicyError struct {
+	Field   string
+	Message string
+}
+
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("invalid policy: %s: %s", e.Field, e.Message)
+}
+
+func ParsePolicy(data []byte) (*Policy, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	var p Policy
+	if err := dec.Decode(&p); err != nil {
+		return nil, &PolicyError{Field: "policy_json", Message: err.Error()}
+	}
+	return &p, nil
+}
+
+// Validate checks the policy against the tiers known to the engine.
+func (p *Policy) Validate(tiers []Tier) error {
+	known := make(map[Tier]bool, len(tiers))
+	for _, t := range tiers {
+		known[t] = true
+	}
+
+	if p.AllowedTiers != nil && len(p.AllowedTiers) == 0 {
+		return &PolicyError{Field: "allowed_tiers", Message: "must not be empty"}
+	}
+	for _, t := range p.AllowedTiers {
+		if !known[t] {
+			return &PolicyError{Field: "allowed_tiers", Message: fmt.Sprintf("unknown tier %q", t)}
+		}
+	}
+	for t, threshold := range p.ConfThresholds {
+		if !known[t] {
+			return &PolicyError{Field: "conf_thresholds", Message: fmt.Sprintf("unknown tier %q", t)}
+		}
+		if threshold < 0 || threshold > 1 {
+			return &PolicyError{Field: "conf_thresholds", Message: fmt.Sprintf("threshold for %s must be between 0 and 1", t)}
+		}
+	}
+	if p.MaxCostCents < 0 {
+		return &PolicyError{Field: "max_cost_cents", Message: "must not be negative"}
+	}
+	if p.LatencySLOMS < 0 {
+		return &PolicyError{Field: "latency_slo_ms", Message: "must not be negative"}
+	}
+	if p.MaxEscalations != nil && *p.MaxEscalations < 0 {
+		return &PolicyError{Field: "max_escalations", Message: "must not be negative"}
+	}
+	switch p.OnBudgetExhausted {
+	case "", BudgetExhaustedDowngrade, BudgetExhaustedReject:
+	default:
+		return &PolicyError{Field: "on_budget_exhausted", Message: fmt.Sprintf("unknown action %q", p.OnBudgetExhausted)}
+	}
+	switch p.Strategy {
+	case "", StrategyThreshold, StrategyUtility, StrategyBandit, StrategyEnsemble:
+	default:
+		return &PolicyError{Field: "strategy", Message: fmt.Sprintf("unknown strategy %q", p.Strategy)}
+	}
+	if p.CostWeight != nil && (*p.CostWeight < 0 || *p.CostWeight > 1) {
+		return &PolicyError{Field: "cost_weight", Message: "must be between 0 and 1"}
+	}
+	if p.TargetAccuracy < 0 || p.TargetAccuracy > 1 {
+		return &PolicyError{Field: "target_accuracy", Message: "must be between 0 and 1"}
+	}
+	switch p.BanditAlgorithm {
+	case "", BanditEpsilonGreedy, BanditThompson:
+	default:
+		return &PolicyError{Field: "bandit_algorithm", Message: fmt.Sprintf("unknown algorithm %q", p.BanditAlgorithm)}
+	}
+	if p.Epsilon != nil && (*p.Epsilon < 0 || *p.Epsilon > 1) {
+		return &PolicyError{Field: "epsilon", Message: "must be between 0 and 1"}
+	}
+	switch p.CascadeMode {
+	case "", CascadeSerial:
+	case CascadeParallel:
+		if p.Strategy == StrategyBandit {
+			return &PolicyError{Field: "cascade_mode", Message: "parallel cascades cannot be used with the bandit strategy"}
+		}
+	default:
+		return &PolicyError{Field: "cascade_mode", Message: fmt.Sprintf("unknown mode %q", p.CascadeMode)}
+	}
+	return nil
+}
+
+func (p *Policy) RejectsWhenBudgetExhausted() bool {
+	return p != nil && p.OnBudgetExhausted == BudgetExhaustedReject
+}
+
+func (p *Policy) strategy() string {
+	if p == nil || p.Strategy == "" {
+		return StrategyThreshold
+	}
+	return p.Strategy
+}
+
+func (p *Policy) threshold(tier Tier) (float64, bool) {
+	if p == nil {
+		return 0, false
+	}
+	threshold, ok := p.ConfThresholds[tier]
+	return threshold, ok
+}
+
+func (p *Policy) costWeight() float64 {
+	if p == nil || p.CostWeight == nil {
+		return defaultCostWeight
+	}
+	return *p.CostWeight
+}
+
+func (p *Policy) banditAlgorithm() string {
+	if p == nil || p.BanditAlgorithm == "" {
+		return BanditEpsilonGreedy
+	}
+	return p.BanditAlgorithm
+}
+
+func (p *Policy) epsilon() float64 {
+	if p == nil || p.Epsilon == nil {
+		return defaultEpsilon
+	}
+	return *p.Epsilon
+}
+
+func (p *Policy) allows(tier Tier) bool {
+	if p == nil || len(p.AllowedTiers) == 0 {
+		return true
+	}
+	for _, t := range p.AllowedTiers {
+		if t == tier {
+			return true
+		}
+	}
+	return false
+}
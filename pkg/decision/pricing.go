@@ -0,0 +1,102 @@
+package decision
+
+import (
+	"encoding/json"
+	"math"
+	"unicode/utf8"
+)
+
+// charsPerToken is the rule-of-thumb ratio used to estimate tokens from
+// text length.
+const charsPerToken = 4
+
+// InputFeatures are the sizes of a request's input that tier cost and
+// latency scale with.
+type InputFeatures struct {
+	Chars  int `json:"chars"`
+	Tokens int `json:"tokens"`
+	Bytes  int `json:"bytes"`
+	Items  int `json:"items"`
+}
+
+// Pricing scales a tier's cost and latency with the size of the input, on
+// top of BaseCostCents and the tier's base latency. Zero coefficients keep
+// the flat per-call model.
+type Pricing struct {
+	CostPer1KTokensCents float64 `json:"cost_per_1k_tokens_cents,omitempty"`
+	CostPerKBCents       float64 `json:"cost_per_kb_cents,omitempty"`
+	CostPerItemCents     float64 `json:"cost_per_item_cents,omitempty"`
+	LatencyPer1KTokensMS float64 `json:"latency_per_1k_tokens_ms,omitempty"`
+	LatencyPerKBMS       float64 `json:"latency_per_kb_ms,omitempty"`
+	LatencyPerItemMS     float64 `json:"latency_per_item_ms,omitempty"`
+}
+
+// Features measures input. Text is counted in characters (the strings of a
+// list input are summed; other values count their JSON encoding), Bytes is
+// the size of the JSON payload and Items the length of a list input.
+func Features(input interface{}) InputFeatures {
+	if input == nil {
+		return InputFeatures{}
+	}
+	var f InputFeatures
+	if b, err := json.Marshal(input); err == nil {
+		f.Bytes = len(b)
+	}
+	switch v := input.(type) {
+	case string:
+		f.Chars = utf8.RuneCountInString(v)
+	case []interface{}:
+		f.Items = len(v)
+		for _, item := range v {
+			f.Chars += textLength(item)
+		}
+	default:
+		f.Chars = f.Bytes
+	}
+	f.Tokens = (f.Chars + charsPerToken - 1) / charsPerToken
+	return f
+}
+
+// features are the features of req's input, or those it carries.
+func (req Request) features() InputFeatures {
+	if req.Features != nil {
+		return *req.Features
+	}
+	return Features(req.Input)
+}
+
+func textLength(v interface{}) int {
+	if s, ok := v.(string); ok {
+		return utf8.RuneCountInString(s)
+	}
+	b, _ := json.Marshal(v)
+	return len(b)
+}
+
+// Cost is what one call to t costs for an input with features f.
+func (t TierConfig) Cost(f InputFeatures) float64 {
+	p := t.Pricing
+	return t.BaseCostCents +
+		p.CostPer1KTokensCents*float64(f.Tokens)/1000 +
+		p.CostPerKBCents*float64(f.Bytes)/1024 +
+		p.CostPerItemCents*float64(f.Items)
+}
+
+// sizeLatency is the latency in ms f adds to t's base latency.
+func (t TierConfig) sizeLatency(f InputFeatures) int {
+	p := t.Pricing
+	return int(math.Round(p.LatencyPer1KTokensMS*float64(f.Tokens)/1000 +
+		p.LatencyPerKBMS*float64(f.Bytes)/1024 +
+		p.LatencyPerItemMS*float64(f.Items)))
+}
+
+// EstimateCost is what calling t costs for req's input.
+func EstimateCost(t TierConfig, req Request) float64 {
+	return t.Cost(req.features())
+}
+
+// estimateLatency is t's timeout stretched by the size of req's input,
+// ignoring telemetry.
+func estimateLatency(t TierConfig, req Request) int {
+	return t.TimeoutMS + t.sizeLatency(req.features())
+}
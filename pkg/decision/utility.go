@@ -0,0 +1,94 @@
+package decision
+
+// decideUtility scores staying with the current answer against calling each
+// more expensive tier the request can afford, and picks the highest score.
+// Staying scores zero; a candidate t scores
+//
+//	(1-w)*gain(t) - w*(cost(t)/budget + latency(t)/slo)
+//
+// where gain is the expected accuracy improvement over the current answer
+// discounted by t's error rate, and w is the policy's cost weight.
+func (e *Engine) decideUtility(current Tier, req Request, telemetry Telemetry, confidence float64, x *Explanation) Decision {
+	cascade := e.Cascade(req.Policy)
+	i := indexOf(cascade, current)
+	if !x.check("tier_allowed", current, 0, 0, i >= 0) {
+		return Decision{Tier: current, Reason: "tier_not_allowed"}
+	}
+	cur := cascade[i]
+
+	budget := EffectiveBudget(req)
+	maxLatencyMS := maxLatency(req)
+	weight := req.Policy.costWeight()
+
+	best := Decision{
+		Tier:                cur.Name,
+		Reason:              "utility_stay",
+		EstimatedCost:       EstimateCost(cur, req),
+		EstimatedLatency:    estimateLatency(cur, req),
+		ConfidenceThreshold: confidenceThreshold(cur, i, req),
+	}
+
+	if !x.check("monthly_budget_available", "", boolValue(!req.BudgetExhausted), 1, !req.BudgetExhausted) {
+		best.Reason = "monthly_budget_exhausted"
+		return best
+	}
+	if n := escalations(cascade, i, req); req.Policy != nil && req.Policy.MaxEscalations != nil &&
+		!x.check("escalations_left", "", float64(n), float64(*req.Policy.MaxEscalations), n < *req.Policy.MaxEscalations) {
+		best.Reason = "max_escalations_reached"
+		return best
+	}
+
+	bestScore := 0.0
+	for j := i + 1; j < len(cascade); j++ {
+		t := cascade[j]
+		cost := EstimateCost(t, req)
+		if !x.check("budget_covers_tier", t.Name, budget, cost, cost <= budget) {
+			continue
+		}
+		if !x.check("tier_not_saturated", t.Name, float64(telemetry.QueueDepth[t.Name]), float64(t.MaxConcurrency), !saturated(t, telemetry)) {
+			continue
+		}
+		latencyMS := expectedLatency(t, req, telemetry)
+		if maxLatencyMS > 0 && !x.check("latency_within_slo", t.Name, float64(latencyMS), float64(maxLatencyMS), latencyMS <= maxLatencyMS) {
+			continue
+		}
+		if left, ok := remainingMS(req); ok && !x.check("deadline_allows", t.Name, float64(latencyMS), float64(left), latencyMS <= left) {
+			continue
+		}
+
+		score := UtilityScore(t, req, telemetry, confidence, budget, maxLatencyMS, weight)
+		if x.check("utility_beats_best", t.Name, score, bestScore, score > bestScore) {
+			bestScore = score
+			best = Decision{
+				Tier:                t.Name,
+				Reason:              "utility_escalated",
+				EstimatedCost:       cost,
+				EstimatedLatency:    latencyMS,
+				ConfidenceThreshold: confidenceThreshold(t, j, req),
+			}
+		}
+	}
+
+	return best
+}
+
+// UtilityScore is the utility of calling t for req when the answer in hand
+// has the given confidence. Accuracy falls back to the tier's confidence threshold
+// when no estimate is configured.
+func UtilityScore(t TierConfig, req Request, telemetry Telemetry, confidence, budget float64, maxLatencyMS int, weight float64) float64 {
+	accuracy := t.ExpectedAccuracy
+	if accuracy == 0 {
+		accuracy = t.DefaultConfThreshold
+	}
+	gain := (1 - telemetry.ErrorRate[t.Name]) * (accuracy - confidence)
+
+	penalty := 0.0
+	if budget > 0 {
+		penalty += EstimateCost(t, req) / budget
+	}
+	if maxLatencyMS > 0 {
+		penalty += float64(expectedLatency(t, req, telemetry)) / float64(maxLatencyMS)
+	}
+
+	return (1-weight)*gain - weight*penalty
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"time"
 
+	"github.com/cost-aware-ml/pkg/decision"
 	"github.com/nats-io/nats.go"
 )
 
@@ -14,17 +15,30 @@ type EventPublisher struct {
 }
 
 type DecisionEvent struct {
-	EventType      string    `json:"event_type"`
-	RequestID      string    `json:"request_id"`
-	UserID         string    `json:"user_id"`
-	TenantID       string    `json:"tenant_id"`
-	Tier           string    `json:"tier"`
-	Reason         string    `json:"reason"`
-	Budget         float64   `json:"budget"`
-	EstimatedCost  float64   `json:"estimated_cost_cents"`
-	Confidence     float64   `json:"confidence,omitempty"`
-	LatencyMS      int       `json:"latency_ms,omitempty"`
-	Timestamp      time.Time `json:"timestamp"`
+	EventType     string             `json:"event_type"`
+	RequestID     string             `json:"request_id"`
+	UserID        string             `json:"user_id"`
+	TenantID      string             `json:"tenant_id"`
+	Tier          string             `json:"tier"`
+	Reason        string             `json:"reason"`
+	Strategy      string             `json:"strategy,omitempty"`
+	Budget        float64            `json:"budget"`
+	EstimatedCost float64            `json:"estimated_cost_cents"`
+	Confidence    float64            `json:"confidence,omitempty"`
+	LatencyMS     int                `json:"latency_ms,omitempty"`
+	Attempts      []decision.Attempt `json:"attempts,omitempty"`
+	EntryTier     string             `json:"entry_tier,omitempty"`
+	// Propensity is the probability the routing policy had of picking the
+	// entry tier; 1 for deterministic strategies.
+	Propensity float64 `json:"propensity"`
+	Context    string  `json:"context,omitempty"`
+	// ConfigVersion is the engine config version the decision was made
+	// with.
+	ConfigVersion int64 `json:"config_version,omitempty"`
+	// Experiment and Arm are set for requests enrolled in an experiment.
+	Experiment string    `json:"experiment,omitempty"`
+	Arm        string    `json:"arm,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
 }
 
 func NewPublisher(natsURL string) (*EventPublisher, error) {
@@ -64,4 +78,3 @@ func (p *EventPublisher) Close() {
 		p.nc.Close()
 	}
 }
-
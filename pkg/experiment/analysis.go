@@ -0,0 +1,243 @@
+package experiment
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/cost-aware-ml/pkg/decision"
+)
+
+// z95 is the normal quantile for two-sided 95% intervals.
+const z95 = 1.96
+
+// Observation is one request served by an experiment arm. Unit is the
+// tenant or user it was assigned by; requests sharing a unit are analyzed
+// as one cluster, and a request with no unit is a cluster of its own.
+type Observation struct {
+	Arm       string
+	Unit      string
+	Tier      decision.Tier
+	CostCents float64
+	LatencyMS float64
+	Correct   *bool
+}
+
+// Interval is an estimate with its 95% confidence interval.
+type Interval struct {
+	Mean float64 `json:"mean"`
+	Low  float64 `json:"low"`
+	High float64 `json:"high"`
+}
+
+// ArmReport summarizes the requests an arm served. Accuracy is over the
+// requests with feedback. The deltas compare the arm to the baseline (the
+// first arm) and are omitted for the baseline itself.
+type ArmReport struct {
+	Arm           string                    `json:"arm"`
+	Requests      int                       `json:"requests"`
+	Units         int                       `json:"units"`
+	CostCents     Interval                  `json:"cost_cents"`
+	LatencyMS     Interval                  `json:"latency_ms"`
+	P95LatencyMS  float64                   `json:"p95_latency_ms"`
+	TierMix       map[decision.Tier]float64 `json:"tier_mix"`
+	Labeled       int                       `json:"labeled"`
+	Accuracy      Interval                  `json:"accuracy"`
+	CostDelta     *Interval                 `json:"cost_delta_cents,omitempty"`
+	LatencyDelta  *Interval                 `json:"latency_delta_ms,omitempty"`
+	AccuracyDelta *Interval                 `json:"accuracy_delta,omitempty"`
+}
+
+type Report struct {
+	Experiment string      `json:"experiment"`
+	Unit       Unit        `json:"unit"`
+	Arms       []ArmReport `json:"arms"`
+}
+
+// Analyze reports on each of arms, in order, from the observations logged
+// under them. Observations of arms not listed are reported after them.
+func Analyze(arms []string, observations []Observation) []ArmReport {
+	byArm := make(map[string][]Observation)
+	for _, o := range observations {
+		byArm[o.Arm] = append(byArm[o.Arm], o)
+	}
+	order := append([]string(nil), arms...)
+	known := make(map[string]bool, len(arms))
+	for _, a := range arms {
+		known[a] = true
+	}
+	var extra []string
+	for a := range byArm {
+		if !known[a] {
+			extra = append(extra, a)
+		}
+	}
+	sort.Strings(extra)
+	order = append(order, extra...)
+
+	reports := make([]ArmReport, 0, len(order))
+	var base summary
+	for i, a := range order {
+		s := summarize(byArm[a])
+		r := s.report(a)
+		if i == 0 {
+			base = s
+		} else {
+			r.CostDelta = meanDelta(s.cost, base.cost)
+			r.LatencyDelta = meanDelta(s.latency, base.latency)
+			r.AccuracyDelta = meanDelta(s.correct, base.correct)
+		}
+		reports = append(reports, r)
+	}
+	return reports
+}
+
+type summary struct {
+	cost, latency values
+	tiers         map[decision.Tier]int
+	units         map[string]bool
+	// correct is 1 or 0 for each labeled request.
+	correct values
+}
+
+func summarize(observations []Observation) summary {
+	s := summary{tiers: make(map[decision.Tier]int), units: make(map[string]bool)}
+	for i, o := range observations {
+		unit := o.Unit
+		if unit == "" {
+			unit = fmt.Sprintf("\x00%d", i)
+		}
+		s.units[unit] = true
+		s.cost.add(o.CostCents, unit)
+		s.latency.add(o.LatencyMS, unit)
+		s.tiers[o.Tier]++
+		if o.Correct != nil {
+			c := 0.0
+			if *o.Correct {
+				c = 1
+			}
+			s.correct.add(c, unit)
+		}
+	}
+	return s
+}
+
+func (s summary) report(arm string) ArmReport {
+	r := ArmReport{
+		Arm:          arm,
+		Requests:     len(s.cost.xs),
+		Units:        len(s.units),
+		CostCents:    meanInterval(s.cost),
+		LatencyMS:    meanInterval(s.latency),
+		P95LatencyMS: percentile(s.latency.xs, 0.95),
+		TierMix:      make(map[decision.Tier]float64, len(s.tiers)),
+		Labeled:      len(s.correct.xs),
+		Accuracy:     accuracyInterval(s.correct),
+	}
+	for t, n := range s.tiers {
+		r.TierMix[t] = float64(n) / float64(len(s.cost.xs))
+	}
+	return r
+}
+
+// values are per-request measurements with the unit each came from.
+type values struct {
+	xs    []float64
+	units []string
+}
+
+func (v *values) add(x float64, unit string) {
+	v.xs = append(v.xs, x)
+	v.units = append(v.units, unit)
+}
+
+// meanVar returns the mean of v and its cluster-robust variance. Every
+// request of a unit lands in the same arm and they tend to resemble each
+// other, so they are not independent draws: summing residuals per unit
+// keeps a few busy units from passing for many independent samples. With
+// one request per unit it is the usual s²/n.
+func meanVar(v values) (float64, float64) {
+	n := len(v.xs)
+	if n == 0 {
+		return 0, 0
+	}
+	mean := 0.0
+	for _, x := range v.xs {
+		mean += x
+	}
+	mean /= float64(n)
+	residuals := make(map[string]float64)
+	for i, x := range v.xs {
+		residuals[v.units[i]] += x - mean
+	}
+	g := len(residuals)
+	if g < 2 {
+		return mean, 0
+	}
+	ss := 0.0
+	for _, r := range residuals {
+		ss += r * r
+	}
+	return mean, float64(g) / float64(g-1) * ss / float64(n*n)
+}
+
+// meanInterval is the normal-approximation interval for the mean of v.
+func meanInterval(v values) Interval {
+	if len(v.xs) == 0 {
+		return Interval{}
+	}
+	mean, variance := meanVar(v)
+	half := z95 * math.Sqrt(variance)
+	return Interval{Mean: mean, Low: mean - half, High: mean + half}
+}
+
+// meanDelta is the interval for the difference of the means of v and base,
+// whose units are disjoint since each unit is assigned to one arm.
+func meanDelta(v, base values) *Interval {
+	if len(v.xs) == 0 || len(base.xs) == 0 {
+		return nil
+	}
+	m1, v1 := meanVar(v)
+	m2, v2 := meanVar(base)
+	d := m1 - m2
+	half := z95 * math.Sqrt(v1+v2)
+	return &Interval{Mean: d, Low: d - half, High: d + half}
+}
+
+// accuracyInterval is the Wilson interval for the share of 1s in correct,
+// over the sample size that clustering leaves effective: n divided by the
+// design effect, the ratio of the clustered to the binomial variance.
+func accuracyInterval(correct values) Interval {
+	n := float64(len(correct.xs))
+	if n == 0 {
+		return Interval{}
+	}
+	p, variance := meanVar(correct)
+	if binomial := p * (1 - p) / n; binomial > 0 && variance > binomial {
+		n *= binomial / variance
+	}
+	return wilson(p, n)
+}
+
+// wilson is the Wilson score interval for a success rate p over n trials,
+// which stays inside [0, 1] for small samples and rates near the edges.
+func wilson(p, n float64) Interval {
+	z2 := z95 * z95
+	denom := 1 + z2/n
+	center := (p + z2/(2*n)) / denom
+	half := z95 * math.Sqrt(p*(1-p)/n+z2/(4*n*n)) / denom
+	return Interval{Mean: p, Low: center - half, High: center + half}
+}
+
+func percentile(xs []float64, q float64) float64 {
+	if len(xs) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), xs...)
+	sort.Float64s(sorted)
+	i := int(math.Ceil(q*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
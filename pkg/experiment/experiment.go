@@ -0,0 +1,182 @@
+package experiment
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+
+	"github.com/cost-aware-ml/pkg/decision"
+)
+
+// Unit is what traffic is split by. Every request of a tenant (or user)
+// lands in the same arm.
+type Unit string
+
+const (
+	UnitTenant Unit = "tenant"
+	UnitUser   Unit = "user"
+)
+
+// Arm is one engine configuration under test. Tiers replaces the live tier
+// config and Policy the tenant's policy; an arm that sets neither is a
+// control arm running on the live config.
+type Arm struct {
+	Name   string                `json:"name"`
+	Weight float64               `json:"weight"`
+	Tiers  []decision.TierConfig `json:"tiers,omitempty"`
+	Policy *decision.Policy      `json:"policy,omitempty"`
+}
+
+// Experiment splits the traffic of eligible tenants between arms in
+// proportion to their weights. An empty Tenants list makes every tenant
+// eligible. The first arm is the baseline the others are compared to.
+type Experiment struct {
+	Name    string   `json:"name"`
+	Unit    Unit     `json:"unit,omitempty"`
+	Tenants []string `json:"tenants,omitempty"`
+	Arms    []Arm    `json:"arms"`
+}
+
+type DefinitionError struct {
+	Experiment string
+	Field      string
+	Message    string
+}
+
+func (e *DefinitionError) Error() string {
+	return fmt.Sprintf("invalid experiment %s: %s: %s", e.Experiment, e.Field, e.Message)
+}
+
+// Parse reads the definition stored for the experiment called name.
+func Parse(name string, data []byte) (*Experiment, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	var e Experiment
+	if err := dec.Decode(&e); err != nil {
+		return nil, &DefinitionError{Experiment: name, Field: "definition_json", Message: err.Error()}
+	}
+	e.Name = name
+	return &e, nil
+}
+
+// Validate checks the definition against the live tiers, which arms
+// without tiers of their own run on.
+func (e *Experiment) Validate(tiers []decision.Tier) error {
+	fail := func(field, format string, args ...interface{}) error {
+		return &DefinitionError{Experiment: e.Name, Field: field, Message: fmt.Sprintf(format, args...)}
+	}
+	if e.Name == "" {
+		return fail("name", "must not be empty")
+	}
+	switch e.Unit {
+	case "", UnitTenant, UnitUser:
+	default:
+		return fail("unit", "unknown unit %q", e.Unit)
+	}
+	if len(e.Arms) < 2 {
+		return fail("arms", "need at least two arms")
+	}
+	seen := make(map[string]bool, len(e.Arms))
+	for i, a := range e.Arms {
+		field := fmt.Sprintf("arms[%d]", i)
+		if a.Name == "" {
+			return fail(field+".name", "must not be empty")
+		}
+		if seen[a.Name] {
+			return fail(field+".name", "duplicate arm %q", a.Name)
+		}
+		seen[a.Name] = true
+		if a.Weight <= 0 {
+			return fail(field+".weight", "must be positive")
+		}
+		names := tiers
+		if a.Tiers != nil {
+			if err := decision.ValidateTiers(a.Tiers); err != nil {
+				return fail(field+".tiers", "%v", err)
+			}
+			names = decision.NewEngineWithTiers(a.Tiers).ConfiguredTierNames()
+		}
+		if a.Policy != nil {
+			if err := a.Policy.Validate(names); err != nil {
+				return fail(field+".policy", "%v", err)
+			}
+		}
+	}
+	return nil
+}
+
+func (e *Experiment) unit() Unit {
+	if e.Unit == "" {
+		return UnitTenant
+	}
+	return e.Unit
+}
+
+// UnitID is the ID of the unit a request is assigned by: its tenant, or its
+// user under a user split.
+func (e *Experiment) UnitID(tenantID, userID string) string {
+	if e.unit() == UnitUser {
+		return userID
+	}
+	return tenantID
+}
+
+func (e *Experiment) Eligible(tenantID string) bool {
+	if len(e.Tenants) == 0 {
+		return true
+	}
+	for _, t := range e.Tenants {
+		if t == tenantID {
+			return true
+		}
+	}
+	return false
+}
+
+// Assign returns the arm a request belongs to. The same tenant (or user)
+// always lands in the same arm for as long as the arms and weights stay the
+// same. Requests from ineligible tenants, or without a user under a user
+// split, are not enrolled.
+func (e *Experiment) Assign(tenantID, userID string) (Arm, bool) {
+	if !e.Eligible(tenantID) {
+		return Arm{}, false
+	}
+	id := e.UnitID(tenantID, userID)
+	if id == "" {
+		return Arm{}, false
+	}
+
+	total := 0.0
+	for _, a := range e.Arms {
+		total += a.Weight
+	}
+	x := bucket(e.Name, id) * total
+	for _, a := range e.Arms {
+		if x < a.Weight {
+			return a, true
+		}
+		x -= a.Weight
+	}
+	return e.Arms[len(e.Arms)-1], true
+}
+
+// Pick enrolls a request in the first of experiments that takes it, so
+// experiments overlapping on a tenant never share a request.
+func Pick(experiments []*Experiment, tenantID, userID string) (*Experiment, Arm, bool) {
+	for _, e := range experiments {
+		if arm, ok := e.Assign(tenantID, userID); ok {
+			return e, arm, true
+		}
+	}
+	return nil, Arm{}, false
+}
+
+// bucket hashes id into [0, 1). Salting with the experiment name keeps the
+// splits of different experiments independent.
+func bucket(name, id string) float64 {
+	sum := sha256.Sum256([]byte(name + "\x00" + id))
+	return float64(binary.BigEndian.Uint64(sum[:8])>>11) / (1 << 53)
+}
@@ -0,0 +1,136 @@
+package experiment
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/cost-aware-ml/pkg/decision"
+)
+
+func TestAssign(t *testing.T) {
+	e := &Experiment{Name: "split", Arms: []Arm{{Name: "control", Weight: 0.8}, {Name: "treatment", Weight: 0.2}}}
+
+	counts := make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		tenant := fmt.Sprintf("tenant-%d", i)
+		arm, ok := e.Assign(tenant, "")
+		if !ok {
+			t.Fatalf("expected %s to be enrolled", tenant)
+		}
+		if again, _ := e.Assign(tenant, "other-user"); again.Name != arm.Name {
+			t.Fatalf("expected %s to stay in %s, got %s", tenant, arm.Name, again.Name)
+		}
+		counts[arm.Name]++
+	}
+	if share := float64(counts["treatment"]) / 10000; math.Abs(share-0.2) > 0.02 {
+		t.Errorf("expected about 20%% in treatment, got %.3f", share)
+	}
+
+	e.Tenants = []string{"acme"}
+	if _, ok := e.Assign("globex", ""); ok {
+		t.Error("expected a tenant outside the experiment not to be enrolled")
+	}
+	e.Unit = UnitUser
+	if _, ok := e.Assign("acme", ""); ok {
+		t.Error("expected a request without a user not to be enrolled in a user split")
+	}
+	if _, ok := e.Assign("acme", "u1"); !ok {
+		t.Error("expected a user of an eligible tenant to be enrolled")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tiers := decision.NewEngine().TierNames()
+	valid := `{"arms": [{"name": "control", "weight": 1}, {"name": "utility", "weight": 1, "policy": {"strategy": "utility"}}]}`
+	e, err := Parse("valid", []byte(valid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Validate(tiers); err != nil {
+		t.Errorf("expected a valid experiment, got %v", err)
+	}
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"one arm", `{"arms": [{"name": "control", "weight": 1}]}`},
+		{"duplicate arm", `{"arms": [{"name": "a", "weight": 1}, {"name": "a", "weight": 1}]}`},
+		{"zero weight", `{"arms": [{"name": "a", "weight": 1}, {"name": "b", "weight": 0}]}`},
+		{"unknown unit", `{"unit": "session", "arms": [{"name": "a", "weight": 1}, {"name": "b", "weight": 1}]}`},
+		{"policy on unknown tier", `{"arms": [{"name": "a", "weight": 1}, {"name": "b", "weight": 1, "policy": {"allowed_tiers": ["tier9"]}}]}`},
+		{"invalid tiers", `{"arms": [{"name": "a", "weight": 1}, {"name": "b", "weight": 1, "tiers": [{"name": "tier0", "enabled": true}]}]}`},
+	}
+	for _, tt := range tests {
+		e, err := Parse(tt.name, []byte(tt.data))
+		if err == nil {
+			err = e.Validate(tiers)
+		}
+		if _, ok := err.(*DefinitionError); !ok {
+			t.Errorf("%s: expected a definition error, got %v", tt.name, err)
+		}
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	var obs []Observation
+	for i := 0; i < 1000; i++ {
+		right, wrong := true, false
+		control := Observation{Arm: "control", Tier: decision.Tier1, CostCents: 2.5, LatencyMS: 200, Correct: &right}
+		if i%5 == 0 {
+			control.Correct = &wrong
+		}
+		cheap := Observation{Arm: "cheap", Tier: decision.Tier0, CostCents: 0.5, LatencyMS: 50, Correct: &right}
+		if i%2 == 0 {
+			cheap.Tier, cheap.CostCents, cheap.Correct = decision.Tier1, 2.5, &wrong
+		}
+		obs = append(obs, control, cheap, Observation{Arm: "stray", Tier: decision.Tier0})
+	}
+
+	reports := Analyze([]string{"control", "cheap"}, obs)
+	if len(reports) != 3 || reports[2].Arm != "stray" {
+		t.Fatalf("expected the listed arms then the stray one, got %+v", reports)
+	}
+	control, cheap := reports[0], reports[1]
+	if control.CostDelta != nil {
+		t.Error("expected no deltas for the baseline")
+	}
+	if control.Accuracy.Low > 0.8 || control.Accuracy.High < 0.8 {
+		t.Errorf("expected the control accuracy interval to cover 0.8, got %+v", control.Accuracy)
+	}
+	if cheap.TierMix[decision.Tier0] != 0.5 {
+		t.Errorf("expected half of the cheap arm on tier0, got %v", cheap.TierMix)
+	}
+	if d := cheap.CostDelta; d == nil || math.Abs(d.Mean+1) > 1e-9 || d.High >= 0 {
+		t.Errorf("expected the cheap arm to cost 1 cent less, got %+v", d)
+	}
+	if d := cheap.AccuracyDelta; d == nil || math.Abs(d.Mean+0.3) > 1e-9 || d.High >= 0 {
+		t.Errorf("expected the cheap arm to be 0.3 less accurate, got %+v", d)
+	}
+}
+
+func TestAnalyzeClustersByUnit(t *testing.T) {
+	// Two tenants per arm with 500 requests each: the costs differ by tenant,
+	// not by arm, so there is far less evidence than 1000 requests suggest.
+	var obs []Observation
+	for i := 0; i < 500; i++ {
+		obs = append(obs,
+			Observation{Arm: "control", Unit: "a", CostCents: 1},
+			Observation{Arm: "control", Unit: "b", CostCents: 3},
+			Observation{Arm: "treatment", Unit: "c", CostCents: 1.5},
+			Observation{Arm: "treatment", Unit: "d", CostCents: 3.5},
+		)
+	}
+	reports := Analyze([]string{"control", "treatment"}, obs)
+	control, treatment := reports[0], reports[1]
+	if control.Units != 2 || control.Requests != 1000 {
+		t.Fatalf("expected 1000 requests from 2 units, got %d from %d", control.Requests, control.Units)
+	}
+	if control.CostCents.Low > 1 || control.CostCents.High < 3 {
+		t.Errorf("expected the control cost interval to span both tenants, got %+v", control.CostCents)
+	}
+	if d := treatment.CostDelta; d == nil || math.Abs(d.Mean-0.5) > 1e-9 || d.Low > 0 {
+		t.Errorf("expected a cost delta of 0.5 that two tenants per arm cannot establish, got %+v", d)
+	}
+}
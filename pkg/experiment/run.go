@@ -0,0 +1,68 @@
+package experiment
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cost-aware-ml/pkg/decision"
+	"github.com/cost-aware-ml/pkg/store"
+)
+
+// Load reads the definitions of the enabled experiments. Definitions that
+// fail to parse or validate against tiers are returned as errors by name and
+// left out.
+func Load(ctx context.Context, s *store.Store, tiers []decision.Tier) ([]*Experiment, map[string]error, error) {
+	stored, err := s.ListExperiments(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	var experiments []*Experiment
+	errs := make(map[string]error)
+	for _, se := range stored {
+		e, err := Parse(se.Name, se.Definition)
+		if err == nil {
+			err = e.Validate(tiers)
+		}
+		if err != nil {
+			errs[se.Name] = err
+			continue
+		}
+		experiments = append(experiments, e)
+	}
+	return experiments, errs, nil
+}
+
+// Run reports on the requests enrolled in the experiment called name since
+// the given time.
+func Run(ctx context.Context, s *store.Store, name string, since time.Time) (Report, error) {
+	stored, err := s.LoadExperiment(ctx, name)
+	if err != nil {
+		return Report{}, err
+	}
+	if stored == nil {
+		return Report{}, fmt.Errorf("no experiment %q", name)
+	}
+	e, err := Parse(name, stored.Definition)
+	if err != nil {
+		return Report{}, err
+	}
+	traces, err := s.LoadExperimentTraces(ctx, name, since)
+	if err != nil {
+		return Report{}, err
+	}
+
+	arms := make([]string, len(e.Arms))
+	for i, a := range e.Arms {
+		arms[i] = a.Name
+	}
+	observations := make([]Observation, len(traces))
+	for i, t := range traces {
+		o := Observation{Arm: t.Arm, Unit: t.Unit, Tier: decision.Tier(t.Tier), CostCents: decision.TotalCost(t.Attempts), Correct: t.Correct}
+		for _, a := range t.Attempts {
+			o.LatencyMS += float64(a.LatencyMS)
+		}
+		observations[i] = o
+	}
+	return Report{Experiment: name, Unit: e.unit(), Arms: Analyze(arms, observations)}, nil
+}
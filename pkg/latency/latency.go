@@ -0,0 +1,101 @@
+package latency
+
+import (
+	"math"
+	"sort"
+)
+
+// hourPriorWeight shrinks an hour's offset towards zero until the hour has
+// seen that many samples.
+const hourPriorWeight = 10
+
+// Sample is one successful worker call: the input's estimated tokens, the
+// hour of day (UTC) it was made in and how long it took.
+type Sample struct {
+	Tokens    int
+	Hour      int
+	LatencyMS float64
+}
+
+// Model predicts a tier's latency as a linear function of input tokens plus
+// an hour-of-day offset, with quantiles taken from the residuals left over.
+type Model struct {
+	Intercept  float64     `json:"intercept_ms"`
+	PerToken   float64     `json:"per_token_ms"`
+	HourOffset [24]float64 `json:"hour_offset_ms"`
+	Samples    int         `json:"samples"`
+	residuals  []float64
+}
+
+// Fit fits a model by least squares on tokens; the slope is kept
+// non-negative so larger inputs never predict faster calls.
+func Fit(samples []Sample) *Model {
+	m := &Model{Samples: len(samples)}
+	if len(samples) == 0 {
+		return m
+	}
+
+	var meanX, meanY float64
+	for _, s := range samples {
+		meanX += float64(s.Tokens)
+		meanY += s.LatencyMS
+	}
+	n := float64(len(samples))
+	meanX, meanY = meanX/n, meanY/n
+
+	var cov, varX float64
+	for _, s := range samples {
+		dx := float64(s.Tokens) - meanX
+		cov += dx * (s.LatencyMS - meanY)
+		varX += dx * dx
+	}
+	if varX > 0 && cov > 0 {
+		m.PerToken = cov / varX
+	}
+	m.Intercept = meanY - m.PerToken*meanX
+
+	var sums, counts [24]float64
+	for _, s := range samples {
+		h := hour(s.Hour)
+		sums[h] += s.LatencyMS - m.Intercept - m.PerToken*float64(s.Tokens)
+		counts[h]++
+	}
+	for h := range sums {
+		m.HourOffset[h] = sums[h] / (counts[h] + hourPriorWeight)
+	}
+
+	m.residuals = make([]float64, len(samples))
+	for i, s := range samples {
+		m.residuals[i] = s.LatencyMS - m.mean(s.Tokens, s.Hour)
+	}
+	sort.Float64s(m.residuals)
+	return m
+}
+
+func (m *Model) mean(tokens, h int) float64 {
+	return m.Intercept + m.PerToken*float64(tokens) + m.HourOffset[hour(h)]
+}
+
+// Quantile is the q-th quantile (0-1) of latency in ms for an input of the
+// given tokens at hour h.
+func (m *Model) Quantile(tokens, h int, q float64) float64 {
+	return math.Max(0, m.mean(tokens, h)+quantile(m.residuals, q))
+}
+
+// quantile interpolates linearly between the closest ranks of sorted.
+func quantile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	q = math.Min(1, math.Max(0, q))
+	pos := q * float64(len(sorted)-1)
+	lo := int(pos)
+	if lo+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[lo] + (pos-float64(lo))*(sorted[lo+1]-sorted[lo])
+}
+
+func hour(h int) int {
+	return ((h % 24) + 24) % 24
+}
@@ -0,0 +1,62 @@
+package latency
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// synthetic samples take 50ms plus 0.1ms per token, 40ms more in the
+// afternoon, with 0-19ms of noise.
+func synthetic() []Sample {
+	var samples []Sample
+	for i := 0; i < 2400; i++ {
+		s := Sample{Tokens: (i % 20) * 100, Hour: i % 24}
+		s.LatencyMS = 50 + 0.1*float64(s.Tokens) + float64(i%7*3)
+		if s.Hour >= 12 {
+			s.LatencyMS += 40
+		}
+		samples = append(samples, s)
+	}
+	return samples
+}
+
+func TestFit(t *testing.T) {
+	m := Fit(synthetic())
+	if math.Abs(m.PerToken-0.1) > 0.01 {
+		t.Errorf("expected about 0.1ms per token, got %v", m.PerToken)
+	}
+	if diff := m.HourOffset[14] - m.HourOffset[2]; math.Abs(diff-40) > 5 {
+		t.Errorf("expected afternoon calls to be about 40ms slower, got %v", diff)
+	}
+
+	median := m.Quantile(1000, 14, 0.5)
+	if math.Abs(median-199) > 6 {
+		t.Errorf("expected median near 199ms, got %v", median)
+	}
+	if p99 := m.Quantile(1000, 14, 0.99); p99 <= median || p99 > 215 {
+		t.Errorf("expected p99 above the median and within the noise, got %v", p99)
+	}
+	if small, large := m.Quantile(100, 14, 0.99), m.Quantile(1900, 14, 0.99); large-small < 150 {
+		t.Errorf("expected larger inputs to predict slower calls, got %v and %v", small, large)
+	}
+}
+
+func TestPredictor(t *testing.T) {
+	p := NewPredictor(100)
+	p.Refit(map[string][]Sample{"tier1": synthetic(), "tier2": synthetic()[:50]})
+
+	afternoon := time.Date(2026, 1, 1, 15, 0, 0, 0, time.UTC)
+	if ms, ok := p.Predict("tier1", 500, afternoon, 0.99); !ok || ms < 150 || ms > 170 {
+		t.Errorf("expected tier1 p99 around 160ms, got %d (%v)", ms, ok)
+	}
+	if _, ok := p.Predict("tier2", 500, afternoon, 0.99); ok {
+		t.Error("expected tier2 to need more samples")
+	}
+	if _, ok := p.Predict("tier0", 500, afternoon, 0.99); ok {
+		t.Error("expected no model for tier0")
+	}
+	if len(p.Report().Models) != 1 {
+		t.Errorf("expected one fitted model, got %d", len(p.Report().Models))
+	}
+}
@@ -0,0 +1,60 @@
+package latency
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Predictor holds the current per-tier models. Tiers with fewer than
+// minSamples calls are left to the engine's other estimates.
+type Predictor struct {
+	mu         sync.RWMutex
+	minSamples int
+	models     map[string]*Model
+	fittedAt   time.Time
+}
+
+func NewPredictor(minSamples int) *Predictor {
+	return &Predictor{minSamples: minSamples, models: make(map[string]*Model)}
+}
+
+// Refit replaces every tier's model with one fitted from samples.
+func (p *Predictor) Refit(samples map[string][]Sample) {
+	models := make(map[string]*Model, len(samples))
+	for tier, s := range samples {
+		if len(s) < p.minSamples {
+			continue
+		}
+		models[tier] = Fit(s)
+	}
+
+	p.mu.Lock()
+	p.models = models
+	p.fittedAt = time.Now()
+	p.mu.Unlock()
+}
+
+// Predict returns the q-th quantile of tier's latency in ms for an input of
+// the given tokens sent at. ok is false when the tier has no model.
+func (p *Predictor) Predict(tier string, tokens int, at time.Time, q float64) (int, bool) {
+	p.mu.RLock()
+	m, ok := p.models[tier]
+	p.mu.RUnlock()
+	if !ok {
+		return 0, false
+	}
+	return int(math.Ceil(m.Quantile(tokens, at.UTC().Hour(), q))), true
+}
+
+type Report struct {
+	FittedAt time.Time         `json:"fitted_at"`
+	Models   map[string]*Model `json:"models"`
+}
+
+// Report returns the fitted coefficients of every tier.
+func (p *Predictor) Report() Report {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return Report{FittedAt: p.fittedAt, Models: p.models}
+}
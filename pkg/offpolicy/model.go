@@ -0,0 +1,138 @@
+package offpolicy
+
+import "github.com/cost-aware-ml/pkg/decision"
+
+const (
+	// minContextSamples is how many requests a context needs on a tier
+	// before its own averages replace the tier-wide ones.
+	minContextSamples = 5
+	// accuracyPriorWeight is how many pseudo-labels the tier's expected
+	// accuracy counts for when smoothing observed accuracy.
+	accuracyPriorWeight = 2.0
+)
+
+type cell struct {
+	n, cost, latency float64
+	confN, conf      float64
+	labeled, correct float64
+}
+
+// Model is the direct-method outcome model used by the doubly-robust
+// estimator: mean cost, latency, confidence and accuracy per context and
+// serving tier, backing off to the tier-wide means and then to the tier
+// configuration.
+type Model struct {
+	tiers    map[decision.Tier]decision.TierConfig
+	contexts map[string]map[decision.Tier]*cell
+	overall  map[decision.Tier]*cell
+}
+
+func Fit(tiers []decision.TierConfig, logs []Logged) *Model {
+	m := &Model{
+		tiers:    make(map[decision.Tier]decision.TierConfig, len(tiers)),
+		contexts: make(map[string]map[decision.Tier]*cell),
+		overall:  make(map[decision.Tier]*cell),
+	}
+	for _, t := range tiers {
+		m.tiers[t.Name] = t
+	}
+
+	for _, l := range logs {
+		y := observed(l)
+		for _, c := range []*cell{m.cell(l.Context, l.Tier), m.tierCell(l.Tier)} {
+			c.n++
+			c.cost += y.CostCents
+			c.latency += y.LatencyMS
+			if l.Correct != nil {
+				c.labeled++
+				c.correct += y.Accuracy
+			}
+		}
+		for _, a := range l.Attempts {
+			if a.Outcome != decision.OutcomeOK {
+				continue
+			}
+			for _, c := range []*cell{m.cell(l.Context, a.Tier), m.tierCell(a.Tier)} {
+				c.confN++
+				c.conf += confidence(a)
+			}
+		}
+	}
+	return m
+}
+
+func (m *Model) cell(context string, tier decision.Tier) *cell {
+	tiers, ok := m.contexts[context]
+	if !ok {
+		tiers = make(map[decision.Tier]*cell)
+		m.contexts[context] = tiers
+	}
+	c, ok := tiers[tier]
+	if !ok {
+		c = &cell{}
+		tiers[tier] = c
+	}
+	return c
+}
+
+func (m *Model) tierCell(tier decision.Tier) *cell {
+	c, ok := m.overall[tier]
+	if !ok {
+		c = &cell{}
+		m.overall[tier] = c
+	}
+	return c
+}
+
+func (m *Model) lookup(context string, tier decision.Tier) (ctx, all *cell) {
+	ctx, all = &cell{}, &cell{}
+	if c, ok := m.contexts[context][tier]; ok {
+		ctx = c
+	}
+	if c, ok := m.overall[tier]; ok {
+		all = c
+	}
+	return ctx, all
+}
+
+func (m *Model) expectedAccuracy(tier decision.Tier) float64 {
+	t := m.tiers[tier]
+	if t.ExpectedAccuracy > 0 {
+		return t.ExpectedAccuracy
+	}
+	return t.DefaultConfThreshold
+}
+
+// Predict is the expected outcome of serving a request in context from tier.
+func (m *Model) Predict(context string, tier decision.Tier) Outcome {
+	ctx, all := m.lookup(context, tier)
+	t := m.tiers[tier]
+
+	o := Outcome{CostCents: t.BaseCostCents, LatencyMS: float64(t.TimeoutMS)}
+	switch {
+	case ctx.n >= minContextSamples:
+		o.CostCents, o.LatencyMS = ctx.cost/ctx.n, ctx.latency/ctx.n
+	case all.n > 0:
+		o.CostCents, o.LatencyMS = all.cost/all.n, all.latency/all.n
+	}
+
+	prior := m.expectedAccuracy(tier)
+	if all.labeled > 0 {
+		prior = (all.correct + accuracyPriorWeight*prior) / (all.labeled + accuracyPriorWeight)
+	}
+	o.Accuracy = (ctx.correct + accuracyPriorWeight*prior) / (ctx.labeled + accuracyPriorWeight)
+	return o
+}
+
+// Confidence is the mean confidence tier returned in context, used when
+// replaying a request through a tier its log never reached.
+func (m *Model) Confidence(context string, tier decision.Tier) float64 {
+	ctx, all := m.lookup(context, tier)
+	switch {
+	case ctx.confN >= minContextSamples:
+		return ctx.conf / ctx.confN
+	case all.confN > 0:
+		return all.conf / all.confN
+	}
+	return m.expectedAccuracy(tier)
+}
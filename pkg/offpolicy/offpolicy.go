@@ -0,0 +1,188 @@
+package offpolicy
+
+import (
+	"github.com/cost-aware-ml/pkg/decision"
+)
+
+// Logged is one request as the logging policy served it: the tier it
+// entered at, the probability the policy had of entering there, the tier
+// that answered and every attempt on the way.
+type Logged struct {
+	Request    decision.Request
+	Context    string
+	Entry      decision.Tier
+	Tier       decision.Tier
+	Propensity float64
+	Attempts   []decision.Attempt
+	Correct    *bool
+}
+
+// Outcome is a mean per request. Accuracy is averaged over labeled requests
+// only.
+type Outcome struct {
+	CostCents float64 `json:"cost_cents"`
+	Accuracy  float64 `json:"accuracy"`
+	LatencyMS float64 `json:"latency_ms"`
+}
+
+type Report struct {
+	Traces  int `json:"traces"`
+	Labeled int `json:"labeled"`
+	// Matched counts requests where the candidate enters at the logged
+	// entry tier and serves from the logged tier; only these carry
+	// importance weight.
+	Matched   int                       `json:"matched"`
+	MaxWeight float64                   `json:"max_weight"`
+	Logged    Outcome                   `json:"logged"`
+	IPS       Outcome                   `json:"ips"`
+	SNIPS     Outcome                   `json:"snips"`
+	DR        Outcome                   `json:"doubly_robust"`
+	Direct    Outcome                   `json:"direct"`
+	TierMix   map[decision.Tier]float64 `json:"tier_mix"`
+}
+
+// observed is the logged outcome of l: the cost and latency of its whole
+// cascade and whether its answer was correct.
+func observed(l Logged) Outcome {
+	o := Outcome{CostCents: decision.TotalCost(l.Attempts)}
+	for _, a := range l.Attempts {
+		o.LatencyMS += float64(a.LatencyMS)
+	}
+	if l.Correct != nil && *l.Correct {
+		o.Accuracy = 1
+	}
+	return o
+}
+
+// confidence is what the engine saw from tier, preferring the calibrated
+// value logged since calibration was introduced.
+func confidence(a decision.Attempt) float64 {
+	if a.CalibratedConfidence > 0 {
+		return a.CalibratedConfidence
+	}
+	return a.Confidence
+}
+
+// Serve replays l through the candidate engine and returns the tier it
+// would have entered at and the one it would have answered from.
+// Confidences come from the log where the tier was reached and from the
+// model otherwise.
+func Serve(engine *decision.Engine, l Logged, m *Model) (entry, served decision.Tier) {
+	observedConf := make(map[decision.Tier]float64)
+	for _, a := range l.Attempts {
+		if a.Outcome == decision.OutcomeOK {
+			observedConf[a.Tier] = confidence(a)
+		}
+	}
+
+	req := l.Request
+	entry = engine.Entry(req, decision.Telemetry{}).Tier
+	if entry == "" {
+		return "", ""
+	}
+	req.EntryTier = entry
+	current := entry
+	for range engine.Tiers() {
+		conf, ok := observedConf[current]
+		if !ok {
+			conf = m.Confidence(l.Context, current)
+		}
+		d := engine.DecideAt(current, req, decision.Telemetry{}, conf)
+		if d.Tier == current {
+			break
+		}
+		current = d.Tier
+	}
+	return entry, current
+}
+
+// Evaluate estimates how the candidate engine would have done on the logged
+// requests. The propensity is the logging policy's for its entry tier and
+// every later step replays deterministically from the logged confidences,
+// so with w = 1[candidate entry and tier = logged entry and tier] /
+// propensity:
+//
+//	IPS    mean(w·y)
+//	SNIPS  sum(w·y) / sum(w)
+//	DR     mean(q(x, candidate) + w·(y − q(x, logged)))
+//	Direct mean(q(x, candidate))
+//
+// where y is the logged outcome and q the model's prediction. Stochastic
+// candidates (the bandit strategy) are replayed once per request, which
+// keeps the estimators unbiased.
+func Evaluate(engine *decision.Engine, logs []Logged, m *Model) Report {
+	r := Report{TierMix: make(map[decision.Tier]float64)}
+	var ips, dr, direct, logged, snipsNum Outcome
+	var weightSum, labeledWeightSum float64
+
+	for _, l := range logs {
+		entry, served := Serve(engine, l, m)
+		if served == "" {
+			continue
+		}
+		r.Traces++
+		r.TierMix[served]++
+
+		p := l.Propensity
+		if p <= 0 {
+			p = 1
+		}
+		w := 0.0
+		if entry == l.Entry && served == l.Tier {
+			w = 1 / p
+			r.Matched++
+			if w > r.MaxWeight {
+				r.MaxWeight = w
+			}
+		}
+
+		y := observed(l)
+		qServed := m.Predict(l.Context, served)
+		qLogged := m.Predict(l.Context, l.Tier)
+
+		weightSum += w
+		logged.CostCents += y.CostCents
+		logged.LatencyMS += y.LatencyMS
+		ips.CostCents += w * y.CostCents
+		ips.LatencyMS += w * y.LatencyMS
+		dr.CostCents += qServed.CostCents + w*(y.CostCents-qLogged.CostCents)
+		dr.LatencyMS += qServed.LatencyMS + w*(y.LatencyMS-qLogged.LatencyMS)
+		direct.CostCents += qServed.CostCents
+		direct.LatencyMS += qServed.LatencyMS
+		snipsNum.CostCents += w * y.CostCents
+		snipsNum.LatencyMS += w * y.LatencyMS
+
+		if l.Correct != nil {
+			r.Labeled++
+			labeledWeightSum += w
+			logged.Accuracy += y.Accuracy
+			ips.Accuracy += w * y.Accuracy
+			dr.Accuracy += qServed.Accuracy + w*(y.Accuracy-qLogged.Accuracy)
+			direct.Accuracy += qServed.Accuracy
+			snipsNum.Accuracy += w * y.Accuracy
+		}
+	}
+
+	n, labeled := float64(r.Traces), float64(r.Labeled)
+	r.Logged = mean(logged, n, labeled)
+	r.IPS = mean(ips, n, labeled)
+	r.DR = mean(dr, n, labeled)
+	r.Direct = mean(direct, n, labeled)
+	r.SNIPS = mean(snipsNum, weightSum, labeledWeightSum)
+	for tier := range r.TierMix {
+		r.TierMix[tier] /= n
+	}
+	return r
+}
+
+func mean(sum Outcome, n, labeled float64) Outcome {
+	var o Outcome
+	if n > 0 {
+		o.CostCents = sum.CostCents / n
+		o.LatencyMS = sum.LatencyMS / n
+	}
+	if labeled > 0 {
+		o.Accuracy = sum.Accuracy / labeled
+	}
+	return o
+}
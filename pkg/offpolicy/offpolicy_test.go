@@ -0,0 +1,178 @@
+package offpolicy
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/cost-aware-ml/pkg/decision"
+)
+
+// uniformLogs simulates a logging policy that picks tier0 or tier1 with
+// equal probability. tier0 answers correctly 60% of the time, tier1 90%.
+func uniformLogs(n int) []Logged {
+	rng := rand.New(rand.NewSource(7))
+	tiers := decision.DefaultTiers()
+	logs := make([]Logged, n)
+	for i := range logs {
+		t := tiers[rng.Intn(2)]
+		accuracy := 0.6
+		if t.Name == decision.Tier1 {
+			accuracy = 0.9
+		}
+		correct := rng.Float64() < accuracy
+		logs[i] = Logged{
+			Request:    decision.Request{Budget: 10},
+			Context:    "acme|normal|lt10",
+			Entry:      t.Name,
+			Tier:       t.Name,
+			Propensity: 0.5,
+			Attempts: []decision.Attempt{{
+				Tier: t.Name, Outcome: decision.OutcomeOK, Confidence: accuracy,
+				LatencyMS: t.TimeoutMS, CostCents: t.BaseCostCents,
+			}},
+			Correct: &correct,
+		}
+	}
+	return logs
+}
+
+func TestEvaluate(t *testing.T) {
+	logs := uniformLogs(4000)
+
+	// Every answer clears tier0's threshold, so the candidate always serves
+	// from tier0.
+	tiers := decision.DefaultTiers()
+	tiers[0].DefaultConfThreshold = 0.5
+	engine := decision.NewEngineWithTiers(tiers)
+
+	r := Evaluate(engine, logs, Fit(engine.Tiers(), logs))
+	if r.TierMix[decision.Tier0] != 1 {
+		t.Fatalf("expected candidate to serve everything from tier0, got %v", r.TierMix)
+	}
+	if r.MaxWeight != 2 {
+		t.Errorf("expected max weight 2, got %v", r.MaxWeight)
+	}
+
+	for name, o := range map[string]Outcome{"ips": r.IPS, "snips": r.SNIPS, "dr": r.DR} {
+		if math.Abs(o.Accuracy-0.6) > 0.05 {
+			t.Errorf("%s: expected accuracy near 0.6, got %.3f", name, o.Accuracy)
+		}
+		if math.Abs(o.CostCents-0.5) > 0.05 {
+			t.Errorf("%s: expected cost near 0.5, got %.3f", name, o.CostCents)
+		}
+		if math.Abs(o.LatencyMS-50) > 5 {
+			t.Errorf("%s: expected latency near 50, got %.1f", name, o.LatencyMS)
+		}
+	}
+	if math.Abs(r.Logged.Accuracy-0.75) > 0.05 {
+		t.Errorf("expected logged accuracy near 0.75, got %.3f", r.Logged.Accuracy)
+	}
+}
+
+func TestServeUsesModelForUnseenTiers(t *testing.T) {
+	engine := decision.NewEngine()
+	correct := true
+	l := Logged{
+		Request:  decision.Request{Budget: 10},
+		Context:  "acme|normal|lt10",
+		Entry:    decision.Tier0,
+		Tier:     decision.Tier0,
+		Attempts: []decision.Attempt{{Tier: decision.Tier0, Outcome: decision.OutcomeOK, Confidence: 0.5}},
+		Correct:  &correct,
+	}
+
+	// tier1 was never reached; its expected accuracy (0.88) clears its
+	// threshold (0.85), so the replay stops there.
+	if _, tier := Serve(engine, l, Fit(engine.Tiers(), []Logged{l})); tier != decision.Tier1 {
+		t.Errorf("expected replay to stop at tier1, got %s", tier)
+	}
+}
+
+func TestEvaluateMatchesEntry(t *testing.T) {
+	// The logging policy enters at tier0 or tier1 with equal probability.
+	// tier0 never clears its threshold, so its requests escalate and are
+	// served from tier1 as well, having paid for both tiers.
+	tiers := decision.DefaultTiers()
+	logs := make([]Logged, 1000)
+	for i := range logs {
+		t0 := decision.Attempt{Tier: decision.Tier0, Outcome: decision.OutcomeOK, Confidence: 0.5, CostCents: tiers[0].BaseCostCents}
+		t1 := decision.Attempt{Tier: decision.Tier1, Outcome: decision.OutcomeOK, Confidence: 0.9, CostCents: tiers[1].BaseCostCents}
+		l := Logged{
+			Request:    decision.Request{Budget: 10},
+			Context:    "acme|normal|lt10",
+			Entry:      decision.Tier1,
+			Tier:       decision.Tier1,
+			Propensity: 0.5,
+			Attempts:   []decision.Attempt{t1},
+		}
+		if i%2 == 0 {
+			l.Entry = decision.Tier0
+			l.Attempts = []decision.Attempt{t0, t1}
+		}
+		logs[i] = l
+	}
+
+	// The candidate enters at tier1, so only the requests logged entering
+	// there tell what it would have paid.
+	engine := decision.NewEngine()
+	for i := range logs {
+		logs[i].Request.Policy = &decision.Policy{AllowedTiers: []decision.Tier{decision.Tier1, decision.Tier2}}
+	}
+	r := Evaluate(engine, logs, Fit(engine.Tiers(), logs))
+	if r.Matched != 500 {
+		t.Errorf("expected only the requests entering at tier1 to match, got %d", r.Matched)
+	}
+	if math.Abs(r.IPS.CostCents-tiers[1].BaseCostCents) > 1e-9 {
+		t.Errorf("expected IPS cost %.2f, got %.3f", tiers[1].BaseCostCents, r.IPS.CostCents)
+	}
+}
+
+func TestServeUsesLoggedContext(t *testing.T) {
+	engine := decision.NewEngine()
+	engine.UseBandit(decision.NewBandit(1))
+	const context = "acme|normal|ge100"
+	for _, tier := range engine.TierNames() {
+		reward := 0.0
+		if tier == decision.Tier1 {
+			reward = 1
+		}
+		engine.Bandit().Update(context, tier, reward)
+	}
+
+	// The input is not logged; without its context the bandit would pick
+	// from an empty lt10 bucket.
+	epsilon := 0.0
+	l := Logged{
+		Request: decision.Request{TenantID: "acme", Budget: 10, Context: context,
+			Policy: &decision.Policy{Strategy: decision.StrategyBandit, Epsilon: &epsilon}},
+		Context: context,
+	}
+	if entry, _ := Serve(engine, l, Fit(engine.Tiers(), nil)); entry != decision.Tier1 {
+		t.Errorf("expected the bandit to pick tier1 in the logged context, got %s", entry)
+	}
+}
+
+func TestServePricesLoggedInput(t *testing.T) {
+	tiers := decision.DefaultTiers()
+	tiers[1].Pricing.CostPer1KTokensCents = 10
+	engine := decision.NewEngineWithTiers(tiers)
+
+	// tier0 falls short of its threshold; escalating is affordable for a
+	// short input but not for the 2000 tokens this request logged.
+	l := Logged{
+		Request:  decision.Request{Budget: 10, Features: &decision.InputFeatures{Tokens: 2000}},
+		Context:  "acme|normal|ge100",
+		Entry:    decision.Tier0,
+		Tier:     decision.Tier0,
+		Attempts: []decision.Attempt{{Tier: decision.Tier0, Outcome: decision.OutcomeOK, Confidence: 0.5}},
+	}
+	m := Fit(engine.Tiers(), []Logged{l})
+	if _, tier := Serve(engine, l, m); tier != decision.Tier0 {
+		t.Errorf("expected the logged input to price tier1 out of the budget, got %s", tier)
+	}
+	l.Request.Features = nil
+	if _, tier := Serve(engine, l, m); tier == decision.Tier0 {
+		t.Errorf("expected an unsized request to escalate")
+	}
+}
@@ -0,0 +1,97 @@
+package offpolicy
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/cost-aware-ml/pkg/decision"
+	"github.com/cost-aware-ml/pkg/store"
+)
+
+type Options struct {
+	TenantID string
+	Since    time.Time
+	// Policy replaces every tenant's stored policy when set.
+	Policy *decision.Policy
+}
+
+// Load reads logged decisions since opts.Since. Each request is paired with
+// opts.Policy or, failing that, its tenant's current policy, so the candidate
+// sees the same per-tenant constraints production would apply. Inputs are
+// not logged, so requests carry their logged context and size in their
+// place.
+func Load(ctx context.Context, s *store.Store, opts Options) ([]Logged, error) {
+	traces, err := s.LoadTraces(ctx, opts.TenantID, opts.Since)
+	if err != nil {
+		return nil, err
+	}
+
+	policies := make(map[string]*decision.Policy)
+	logs := make([]Logged, 0, len(traces))
+	for _, t := range traces {
+		policy := opts.Policy
+		if policy == nil {
+			cached, ok := policies[t.TenantID]
+			if !ok {
+				cached, err = s.LoadPolicy(ctx, t.TenantID)
+				if err != nil {
+					return nil, err
+				}
+				policies[t.TenantID] = cached
+			}
+			policy = cached
+		}
+
+		input := t.Input
+		logs = append(logs, Logged{
+			Request: decision.Request{
+				RequestID: t.RequestID,
+				TenantID:  t.TenantID,
+				Priority:  priorityOf(t.Context),
+				Budget:    t.Budget,
+				Context:   t.Context,
+				Features:  &input,
+				Policy:    policy,
+			},
+			Context:    t.Context,
+			Entry:      entryOf(t),
+			Tier:       decision.Tier(t.Tier),
+			Propensity: t.Propensity,
+			Attempts:   t.Attempts,
+			Correct:    t.Correct,
+		})
+	}
+	return logs, nil
+}
+
+// Run evaluates the candidate engine against logged traffic.
+func Run(ctx context.Context, s *store.Store, candidate *decision.Engine, opts Options) (Report, error) {
+	logs, err := Load(ctx, s, opts)
+	if err != nil {
+		return Report{}, err
+	}
+	return Evaluate(candidate, logs, Fit(candidate.Tiers(), logs)), nil
+}
+
+// entryOf is the tier t entered at. Requests logged before it was recorded
+// fall back to their first attempt, which is the entry tier unless the
+// cascade hedged or ran in parallel.
+func entryOf(t store.RequestTrace) decision.Tier {
+	if t.EntryTier != "" {
+		return decision.Tier(t.EntryTier)
+	}
+	if len(t.Attempts) > 0 {
+		return t.Attempts[0].Tier
+	}
+	return decision.Tier(t.Tier)
+}
+
+// priorityOf recovers the priority from a tenant|priority|size context.
+func priorityOf(context string) string {
+	parts := strings.Split(context, "|")
+	if len(parts) != 3 {
+		return ""
+	}
+	return parts[1]
+}
@@ -0,0 +1,173 @@
+package prerouting
+
+import (
+	"math/bits"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cost-aware-ml/pkg/decision"
+)
+
+// Trace is a logged request that entered the cascade at its cheapest tier:
+// the measured input and every tier call it made.
+type Trace struct {
+	Input    decision.InputFeatures
+	Attempts []decision.Attempt
+}
+
+// Bucket holds what requests of one input size cost from each starting
+// tier. Requests are bucketed by the bit length of their token estimate, so
+// bucket b covers 2^(b-1) to 2^b-1 tokens.
+type Bucket struct {
+	MinTokens int `json:"min_tokens"`
+	MaxTokens int `json:"max_tokens"`
+	Samples   int `json:"samples"`
+	// Reached is the share of requests that called each tier.
+	Reached map[decision.Tier]float64 `json:"reached"`
+	// StartCost is the expected cost in cents of starting at each tier.
+	StartCost map[decision.Tier]float64 `json:"start_cost_cents"`
+	Start     decision.Tier             `json:"start"`
+}
+
+// Router predicts, from the size of an input, the tier a request is
+// cheapest to start at in expectation. A request started higher up skips
+// the calls below that tier but pays for the starting tier even when a
+// cheaper one would have answered; buckets with fewer than minSamples
+// traces always start at the bottom.
+type Router struct {
+	mu         sync.RWMutex
+	minSamples int
+	entry      decision.Tier
+	buckets    map[int]*Bucket
+	fittedAt   time.Time
+}
+
+func NewRouter(minSamples int) *Router {
+	return &Router{minSamples: minSamples, buckets: make(map[int]*Bucket)}
+}
+
+func bucketOf(tokens int) int {
+	return bits.Len(uint(tokens))
+}
+
+// Refit replaces the buckets with ones fitted from traces that entered at
+// tiers[0].
+func (r *Router) Refit(tiers []decision.TierConfig, traces []Trace) {
+	buckets := make(map[int]*Bucket)
+	if len(tiers) == 0 {
+		r.swap("", buckets)
+		return
+	}
+	position := make(map[decision.Tier]int, len(tiers))
+	for i, t := range tiers {
+		position[t.Name] = i
+	}
+
+	type totals struct {
+		n       float64
+		reached []float64
+		cost    []float64
+	}
+	sums := make(map[int]*totals)
+	for _, tr := range traces {
+		if len(tr.Attempts) == 0 || tr.Attempts[0].Tier != tiers[0].Name {
+			continue
+		}
+		b := bucketOf(tr.Input.Tokens)
+		s, ok := sums[b]
+		if !ok {
+			s = &totals{reached: make([]float64, len(tiers)), cost: make([]float64, len(tiers))}
+			sums[b] = s
+		}
+		s.n++
+
+		// Starting at tier j costs what the trace spent from j up, or one
+		// call to j if the trace stopped below it.
+		highest := -1
+		suffix := make([]float64, len(tiers))
+		for _, a := range tr.Attempts {
+			p, ok := position[a.Tier]
+			if !ok {
+				continue
+			}
+			if p > highest {
+				highest = p
+			}
+			for j := 0; j <= p; j++ {
+				suffix[j] += a.CostCents
+			}
+		}
+		for j, t := range tiers {
+			if j <= highest {
+				s.reached[j]++
+				s.cost[j] += suffix[j]
+			} else {
+				s.cost[j] += t.Cost(tr.Input)
+			}
+		}
+	}
+
+	for b, s := range sums {
+		bucket := &Bucket{
+			Samples:   int(s.n),
+			Reached:   make(map[decision.Tier]float64, len(tiers)),
+			StartCost: make(map[decision.Tier]float64, len(tiers)),
+			Start:     tiers[0].Name,
+		}
+		if b > 0 {
+			bucket.MinTokens = 1 << (b - 1)
+		}
+		bucket.MaxTokens = 1<<b - 1
+		best := -1.0
+		for j, t := range tiers {
+			bucket.Reached[t.Name] = s.reached[j] / s.n
+			bucket.StartCost[t.Name] = s.cost[j] / s.n
+			if best < 0 || bucket.StartCost[t.Name] < best {
+				best = bucket.StartCost[t.Name]
+				bucket.Start = t.Name
+			}
+		}
+		buckets[b] = bucket
+	}
+	r.swap(tiers[0].Name, buckets)
+}
+
+func (r *Router) swap(entry decision.Tier, buckets map[int]*Bucket) {
+	r.mu.Lock()
+	r.entry = entry
+	r.buckets = buckets
+	r.fittedAt = time.Now()
+	r.mu.Unlock()
+}
+
+// Route returns the tier an input of features f should start at and the
+// expected saving in cents over starting at the bottom. ok is false when
+// the bottom tier is the best start or the bucket has too few samples.
+func (r *Router) Route(f decision.InputFeatures) (decision.Tier, float64, bool) {
+	r.mu.RLock()
+	b, ok := r.buckets[bucketOf(f.Tokens)]
+	entry := r.entry
+	r.mu.RUnlock()
+	if !ok || b.Samples < r.minSamples || b.Start == entry {
+		return "", 0, false
+	}
+	return b.Start, b.StartCost[entry] - b.StartCost[b.Start], true
+}
+
+type Report struct {
+	FittedAt time.Time `json:"fitted_at"`
+	Buckets  []*Bucket `json:"buckets"`
+}
+
+// Report returns the fitted buckets from smallest to largest input.
+func (r *Router) Report() Report {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	report := Report{FittedAt: r.fittedAt}
+	for _, b := range r.buckets {
+		report.Buckets = append(report.Buckets, b)
+	}
+	sort.Slice(report.Buckets, func(i, j int) bool { return report.Buckets[i].MinTokens < report.Buckets[j].MinTokens })
+	return report
+}
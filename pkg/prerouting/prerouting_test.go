@@ -0,0 +1,60 @@
+package prerouting
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cost-aware-ml/pkg/decision"
+)
+
+func TestRouter(t *testing.T) {
+	tiers := decision.DefaultTiers()
+	tier0 := decision.Attempt{Tier: decision.Tier0, Outcome: decision.OutcomeOK, CostCents: 0.5}
+	tier1 := decision.Attempt{Tier: decision.Tier1, Outcome: decision.OutcomeOK, CostCents: 2.0}
+
+	var traces []Trace
+	for i := 0; i < 100; i++ {
+		// Short inputs almost always stop at tier0.
+		short := Trace{Input: decision.InputFeatures{Tokens: 2}, Attempts: []decision.Attempt{tier0}}
+		if i%10 == 0 {
+			short.Attempts = append(short.Attempts, tier1)
+		}
+		// Long inputs almost always escalate.
+		long := Trace{Input: decision.InputFeatures{Tokens: 200}, Attempts: []decision.Attempt{tier0, tier1}}
+		if i%10 == 0 {
+			long.Attempts = long.Attempts[:1]
+		}
+		traces = append(traces, short, long)
+	}
+	// Requests that did not enter at tier0 say nothing about skipping it.
+	traces = append(traces, Trace{Input: decision.InputFeatures{Tokens: 2}, Attempts: []decision.Attempt{tier1}})
+
+	r := NewRouter(50)
+	r.Refit(tiers, traces)
+
+	if _, _, ok := r.Route(decision.InputFeatures{Tokens: 3}); ok {
+		t.Error("expected short inputs to start at tier0")
+	}
+	tier, saving, ok := r.Route(decision.InputFeatures{Tokens: 150})
+	if !ok || tier != decision.Tier1 {
+		t.Fatalf("expected long inputs to start at tier1, got %q (%v)", tier, ok)
+	}
+	// From tier0: 0.5 + 0.9*2.0; from tier1: 2.0 for everyone.
+	if math.Abs(saving-0.3) > 1e-9 {
+		t.Errorf("expected a saving of 0.3 cents, got %v", saving)
+	}
+	if _, _, ok := r.Route(decision.InputFeatures{Tokens: 5000}); ok {
+		t.Error("expected unseen input sizes to start at tier0")
+	}
+
+	sparse := NewRouter(500)
+	sparse.Refit(tiers, traces)
+	if _, _, ok := sparse.Route(decision.InputFeatures{Tokens: 150}); ok {
+		t.Error("expected too few samples to keep tier0")
+	}
+
+	report := r.Report()
+	if len(report.Buckets) != 2 || report.Buckets[0].Samples != 100 || report.Buckets[1].MinTokens != 128 {
+		t.Errorf("unexpected report %+v", report.Buckets)
+	}
+}
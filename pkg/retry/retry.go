@@ -30,6 +30,9 @@ func Retry(config RetryConfig, fn func() error) error {
 		if err == nil {
 			return nil
 		}
+		if p, ok := err.(*PermanentError); ok {
+			return p.Err
+		}
 		
 		lastErr = err
 		
@@ -47,3 +50,17 @@ func Retry(config RetryConfig, fn func() error) error {
 	return lastErr
 }
 
+
+// PermanentError stops Retry immediately and is unwrapped before being
+// returned to the caller.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func Permanent(err error) error {
+	return &PermanentError{Err: err}
+}
@@ -0,0 +1,214 @@
+package shadow
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/cost-aware-ml/pkg/decision"
+)
+
+// costTolerance is the difference in estimated cost, in cents, below which
+// two outcomes count as costing the same.
+const costTolerance = 1e-6
+
+// Disagreement kinds, from most to least severe. A disagreement is reported
+// under the first kind that applies.
+const (
+	KindTier   = "tier"
+	KindReason = "reason"
+	KindCost   = "cost"
+)
+
+// Candidate is an engine configuration evaluated in the shadow of the live
+// one. Tiers replaces the live tier config and Policy every tenant's
+// policy; either may be left out.
+type Candidate struct {
+	Name   string                `json:"name"`
+	Tiers  []decision.TierConfig `json:"tiers,omitempty"`
+	Policy *decision.Policy      `json:"policy,omitempty"`
+}
+
+func ParseCandidate(data []byte) (*Candidate, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	var c Candidate
+	if err := dec.Decode(&c); err != nil {
+		return nil, &decision.ConfigError{Field: "shadow", Message: err.Error()}
+	}
+	return &c, nil
+}
+
+// Validate checks the candidate against the live tiers, which it runs on
+// when it has no tiers of its own.
+func (c *Candidate) Validate(tiers []decision.Tier) error {
+	if c.Name == "" {
+		return &decision.ConfigError{Field: "shadow.name", Message: "must not be empty"}
+	}
+	if c.Tiers != nil {
+		if err := decision.ValidateTiers(c.Tiers); err != nil {
+			return err
+		}
+		tiers = decision.NewEngineWithTiers(c.Tiers).ConfiguredTierNames()
+	}
+	if c.Policy != nil {
+		if err := c.Policy.Validate(tiers); err != nil {
+			return &decision.ConfigError{Field: "shadow.policy", Message: err.Error()}
+		}
+	}
+	return nil
+}
+
+// Outcome is where an engine's cascade ends for one request. Observed is
+// false when the engine would go on to a tier the live cascade never got an
+// answer from; Tier is then that tier and the final answer is unknown.
+// EstimatedCostCents sums EstimateCost over the tiers called, as a serial
+// cascade would pay for them.
+type Outcome struct {
+	Tier               decision.Tier   `json:"tier"`
+	Reason             string          `json:"reason"`
+	EstimatedCostCents float64         `json:"estimated_cost_cents"`
+	Path               []decision.Tier `json:"path"`
+	Observed           bool            `json:"observed"`
+	// Concurrent is set when the live cascade hedged or called tiers in
+	// parallel, so its path is not one a serial replay can take.
+	Concurrent bool `json:"concurrent,omitempty"`
+}
+
+// Live is the outcome of the cascade engine ran for req. Tiers skipped on
+// an open circuit were never called and are left out of the path.
+func Live(engine *decision.Engine, req decision.Request, tier decision.Tier, reason string, attempts []decision.Attempt, concurrent bool) Outcome {
+	o := Outcome{Tier: tier, Reason: reason, Observed: true, Concurrent: concurrent}
+	for _, a := range attempts {
+		if a.Outcome == decision.OutcomeCircuitOpen {
+			continue
+		}
+		o.Path = append(o.Path, a.Tier)
+		if t, ok := engine.Config(a.Tier); ok {
+			o.EstimatedCostCents += decision.EstimateCost(t, req)
+		}
+	}
+	return o
+}
+
+// Replay runs req through engine without calling any worker: each tier the
+// engine visits answers with the confidence the live cascade got from it.
+// The replay stops at the first tier the live cascade has no answer from.
+func Replay(engine *decision.Engine, req decision.Request, telemetry decision.Telemetry, attempts []decision.Attempt) Outcome {
+	observed := make(map[decision.Tier]float64)
+	for _, a := range attempts {
+		if a.Outcome == decision.OutcomeOK {
+			observed[a.Tier] = confidence(a)
+		}
+	}
+
+	entry := engine.Entry(req, telemetry)
+	req.EntryTier = entry.Tier
+	o := Outcome{Tier: entry.Tier, Reason: entry.Reason}
+	// Escalations only move up the cascade, so the walk ends.
+	for current := entry.Tier; current != ""; {
+		o.Path = append(o.Path, current)
+		if t, ok := engine.Config(current); ok {
+			o.EstimatedCostCents += decision.EstimateCost(t, req)
+		}
+		conf, ok := observed[current]
+		if !ok {
+			return o
+		}
+		d := engine.DecideAt(current, req, telemetry, conf)
+		o.Tier, o.Reason = d.Tier, d.Reason
+		if d.Tier == current {
+			o.Observed = true
+			return o
+		}
+		current = d.Tier
+	}
+	return o
+}
+
+func confidence(a decision.Attempt) float64 {
+	if a.CalibratedConfidence > 0 {
+		return a.CalibratedConfidence
+	}
+	return a.Confidence
+}
+
+// Compare returns the kind of disagreement between the live and candidate
+// outcomes, or "" when they agree. Costs are not compared when the live
+// cascade ran tiers concurrently.
+func Compare(live, candidate Outcome) string {
+	switch {
+	case live.Tier != candidate.Tier:
+		return KindTier
+	case live.Reason != candidate.Reason:
+		return KindReason
+	case !live.Concurrent && math.Abs(live.EstimatedCostCents-candidate.EstimatedCostCents) > costTolerance:
+		return KindCost
+	}
+	return ""
+}
+
+// Disagreement is a request the candidate decided differently.
+type Disagreement struct {
+	RequestID string    `json:"request_id"`
+	TenantID  string    `json:"tenant_id"`
+	Candidate string    `json:"candidate"`
+	Kind      string    `json:"kind"`
+	Live      Outcome   `json:"live"`
+	Shadow    Outcome   `json:"shadow"`
+	At        time.Time `json:"at"`
+}
+
+// Log keeps the most recent disagreements for review.
+type Log struct {
+	mu      sync.Mutex
+	entries []Disagreement
+	next    int
+	full    bool
+}
+
+func NewLog(size int) *Log {
+	return &Log{entries: make([]Disagreement, size)}
+}
+
+func (l *Log) Add(d Disagreement) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.entries) == 0 {
+		return
+	}
+	l.entries[l.next] = d
+	l.next = (l.next + 1) % len(l.entries)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// Recent returns the logged disagreements, newest first, optionally only
+// those of one kind.
+func (l *Log) Recent(kind string) []Disagreement {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := l.next
+	if l.full {
+		n = len(l.entries)
+	}
+	recent := make([]Disagreement, 0, n)
+	for i := 1; i <= n; i++ {
+		d := l.entries[(l.next-i+len(l.entries))%len(l.entries)]
+		if kind == "" || d.Kind == kind {
+			recent = append(recent, d)
+		}
+	}
+	return recent
+}
+
+func (d Disagreement) String() string {
+	return fmt.Sprintf("%s (tenant %s): live %s/%s %.3f cents, %s %s/%s %.3f cents",
+		d.RequestID, d.TenantID, d.Live.Tier, d.Live.Reason, d.Live.EstimatedCostCents,
+		d.Candidate, d.Shadow.Tier, d.Shadow.Reason, d.Shadow.EstimatedCostCents)
+}
@@ -0,0 +1,97 @@
+package shadow
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/cost-aware-ml/pkg/decision"
+)
+
+func attempt(t decision.TierConfig, conf float64) decision.Attempt {
+	return decision.Attempt{Tier: t.Name, Outcome: decision.OutcomeOK, Confidence: conf, CalibratedConfidence: conf, CostCents: t.BaseCostCents}
+}
+
+func TestReplay(t *testing.T) {
+	tiers := decision.DefaultTiers()
+	live := decision.NewEngineWithTiers(tiers)
+	req := decision.Request{RequestID: "r1", Budget: 10.0}
+
+	// tier0 answered 0.7, below its 0.75 threshold, so the live cascade
+	// escalated and tier1 accepted 0.9.
+	attempts := []decision.Attempt{attempt(tiers[0], 0.7), attempt(tiers[1], 0.9)}
+	final := live.DecideAt(decision.Tier1, req, decision.Telemetry{}, 0.9)
+	liveOutcome := Live(live, req, final.Tier, final.Reason, attempts, false)
+
+	same := Replay(live, req, decision.Telemetry{}, attempts)
+	if kind := Compare(liveOutcome, same); kind != "" {
+		t.Errorf("expected the live config to agree with itself, got %s: %+v", kind, same)
+	}
+	// What was billed does not matter: both sides are estimated the same way.
+	billed := append([]decision.Attempt(nil), attempts...)
+	billed[1].CostCents = 7.5
+	if kind := Compare(Live(live, req, final.Tier, final.Reason, billed, false), same); kind != "" {
+		t.Errorf("expected live cost to be estimated like the replay's, got %s", kind)
+	}
+
+	lenient := decision.DefaultTiers()
+	lenient[0].DefaultConfThreshold = 0.6
+	out := Replay(decision.NewEngineWithTiers(lenient), req, decision.Telemetry{}, attempts)
+	if out.Tier != decision.Tier0 || !out.Observed || Compare(liveOutcome, out) != KindTier {
+		t.Errorf("expected a lenient candidate to stop at tier0, got %+v", out)
+	}
+	if out.EstimatedCostCents != tiers[0].BaseCostCents {
+		t.Errorf("expected the candidate to pay for tier0 only, got %.2f", out.EstimatedCostCents)
+	}
+
+	strict := decision.DefaultTiers()
+	strict[1].DefaultConfThreshold = 0.95
+	out = Replay(decision.NewEngineWithTiers(strict), req, decision.Telemetry{}, attempts)
+	if out.Tier != decision.Tier2 || out.Observed {
+		t.Errorf("expected a strict candidate to go on to tier2, which the live cascade never called, got %+v", out)
+	}
+	if len(out.Path) != 3 {
+		t.Errorf("expected the candidate path to cover every tier, got %v", out.Path)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	live := Outcome{Tier: decision.Tier1, Reason: "confidence_met", EstimatedCostCents: 2.5}
+	tests := []struct {
+		candidate Outcome
+		kind      string
+	}{
+		{Outcome{Tier: decision.Tier1, Reason: "confidence_met", EstimatedCostCents: 2.5}, ""},
+		{Outcome{Tier: decision.Tier0, Reason: "confidence_met", EstimatedCostCents: 0.5}, KindTier},
+		{Outcome{Tier: decision.Tier1, Reason: "prerouted", EstimatedCostCents: 2.0}, KindReason},
+		{Outcome{Tier: decision.Tier1, Reason: "confidence_met", EstimatedCostCents: 2.0}, KindCost},
+	}
+	for _, tt := range tests {
+		if kind := Compare(live, tt.candidate); kind != tt.kind {
+			t.Errorf("%+v: expected %q, got %q", tt.candidate, tt.kind, kind)
+		}
+	}
+
+	hedged := live
+	hedged.Concurrent = true
+	if kind := Compare(hedged, tests[3].candidate); kind != "" {
+		t.Errorf("expected costs of a hedged cascade not to be compared, got %q", kind)
+	}
+}
+
+func TestLog(t *testing.T) {
+	l := NewLog(3)
+	for i := 0; i < 5; i++ {
+		kind := KindTier
+		if i%2 == 1 {
+			kind = KindCost
+		}
+		l.Add(Disagreement{RequestID: fmt.Sprintf("r%d", i), Kind: kind})
+	}
+	recent := l.Recent("")
+	if len(recent) != 3 || recent[0].RequestID != "r4" || recent[2].RequestID != "r2" {
+		t.Errorf("expected the three newest disagreements, newest first, got %+v", recent)
+	}
+	if tier := l.Recent(KindTier); len(tier) != 2 {
+		t.Errorf("expected two tier disagreements, got %+v", tier)
+	}
+}
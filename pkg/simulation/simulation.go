@@ -0,0 +1,244 @@
+package simulation
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/cost-aware-ml/pkg/decision"
+)
+
+// TraceRequest is one line of a request trace: an /infer body, optionally
+// with what each tier answered when the request was recorded.
+type TraceRequest struct {
+	RequestID    string                            `json:"request_id"`
+	UserID       string                            `json:"user_id"`
+	TenantID     string                            `json:"tenant_id"`
+	Input        interface{}                       `json:"input"`
+	Priority     string                            `json:"priority,omitempty"`
+	Budget       float64                           `json:"budget,omitempty"`
+	MaxLatencyMS int                               `json:"max_latency_ms,omitempty"`
+	MaxCostCents float64                           `json:"max_cost_cents,omitempty"`
+	Outcomes     map[decision.Tier]RecordedOutcome `json:"outcomes,omitempty"`
+}
+
+type RecordedOutcome struct {
+	Confidence float64 `json:"confidence"`
+	LatencyMS  int     `json:"latency_ms"`
+	Correct    *bool   `json:"correct,omitempty"`
+	Error      bool    `json:"error,omitempty"`
+}
+
+// ReadTrace parses a JSON-lines trace, skipping blank lines. Every record
+// must carry an input: the workers and the cost model both depend on it, and
+// a file of other JSON lines would otherwise replay as empty requests.
+func ReadTrace(r io.Reader) ([]TraceRequest, error) {
+	var trace []TraceRequest
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var req TraceRequest
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if req.Input == nil || req.Input == "" {
+			return nil, fmt.Errorf("line %d: no input", line)
+		}
+		trace = append(trace, req)
+	}
+	return trace, scanner.Err()
+}
+
+// ReadTraceFile reads a trace from path, or from stdin when path is "-".
+func ReadTraceFile(path string) ([]TraceRequest, error) {
+	if path == "-" {
+		return ReadTrace(os.Stdin)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadTrace(f)
+}
+
+// Config is one engine configuration to simulate. Tiers default to
+// decision.DefaultTiers, Policy applies to every request and DefaultBudget
+// stands in for the tenant's per-request budget.
+type Config struct {
+	Name          string                `json:"name"`
+	Tiers         []decision.TierConfig `json:"tiers,omitempty"`
+	Policy        *decision.Policy      `json:"policy,omitempty"`
+	DefaultBudget float64               `json:"default_budget,omitempty"`
+}
+
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	var c Config
+	if err := json.Unmarshal(data, &c); err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if c.Name == "" {
+		c.Name = path
+	}
+	return c, nil
+}
+
+// Engine builds the configuration's engine and validates its policy.
+func (c Config) Engine() (*decision.Engine, error) {
+	tiers := c.Tiers
+	if len(tiers) == 0 {
+		tiers = decision.DefaultTiers()
+	}
+	engine := decision.NewEngineWithTiers(tiers)
+	if c.Policy != nil {
+		if err := c.Policy.Validate(engine.ConfiguredTierNames()); err != nil {
+			return nil, err
+		}
+	}
+	return engine, nil
+}
+
+type Result struct {
+	Name          string                    `json:"name"`
+	Requests      int                       `json:"requests"`
+	Failed        int                       `json:"failed"`
+	CostCents     float64                   `json:"cost_cents"`
+	MeanCostCents float64                   `json:"mean_cost_cents"`
+	Accuracy      float64                   `json:"accuracy"`
+	Escalations   int                       `json:"escalations"`
+	SLOViolations int                       `json:"slo_violations"`
+	P50LatencyMS  int                       `json:"p50_latency_ms"`
+	P99LatencyMS  int                       `json:"p99_latency_ms"`
+	TierMix       map[decision.Tier]float64 `json:"tier_mix"`
+	Reasons       map[string]int            `json:"reasons"`
+}
+
+// Run replays the trace through the configuration the way the controlplane
+// serves a request: start at the engine's entry tier, call the worker,
+// decide, and escalate until the engine keeps an answer. A failed call fails
+// the request. Workers that report telemetry feed it to the engine. Latency
+// is the sum of the calls; accuracy averages the served answers' probability
+// of being correct.
+func Run(c Config, trace []TraceRequest, worker Worker) (Result, error) {
+	engine, err := c.Engine()
+	if err != nil {
+		return Result{}, err
+	}
+
+	r := Result{Name: c.Name, TierMix: make(map[decision.Tier]float64), Reasons: make(map[string]int)}
+	telemetry := decision.Telemetry{}
+	if src, ok := worker.(TelemetrySource); ok {
+		telemetry = src.Telemetry()
+	}
+	var latencies []int
+	served := 0
+
+	for _, tr := range trace {
+		r.Requests++
+		req := decision.Request{
+			RequestID:     tr.RequestID,
+			UserID:        tr.UserID,
+			TenantID:      tr.TenantID,
+			Input:         tr.Input,
+			Priority:      tr.Priority,
+			MaxLatencyMS:  tr.MaxLatencyMS,
+			MaxCostCents:  tr.MaxCostCents,
+			Budget:        tr.Budget,
+			DefaultBudget: c.DefaultBudget,
+			Policy:        c.Policy,
+		}
+
+		entry := engine.Entry(req, telemetry)
+		if entry.Tier == "" {
+			r.Failed++
+			continue
+		}
+		req.EntryTier = entry.Tier
+
+		current := entry.Tier
+		var attempts []decision.Attempt
+		latency := 0
+		for {
+			cfg, _ := engine.Config(current)
+			resp := worker.Call(cfg, tr)
+			latency += resp.LatencyMS
+			if resp.Failed {
+				attempts = append(attempts, decision.Attempt{Tier: current, Outcome: decision.OutcomeError, LatencyMS: resp.LatencyMS, CostCents: decision.AttemptCost(cfg, req, decision.OutcomeError)})
+				r.Failed++
+				break
+			}
+			attempts = append(attempts, decision.Attempt{Tier: current, Outcome: decision.OutcomeOK, Confidence: resp.Confidence, LatencyMS: resp.LatencyMS, CostCents: decision.AttemptCost(cfg, req, decision.OutcomeOK)})
+
+			d := engine.DecideAt(current, req, telemetry, resp.Confidence)
+			if d.Tier != current {
+				r.Escalations++
+				current = d.Tier
+				continue
+			}
+
+			served++
+			r.TierMix[current]++
+			r.Reasons[d.Reason]++
+			r.Accuracy += resp.Accuracy
+			if entry.Strategy == decision.StrategyBandit {
+				engine.Bandit().Update(entry.Context, current, engine.Reward(req, resp.Confidence, decision.TotalCost(attempts)))
+			}
+			break
+		}
+
+		r.CostCents += decision.TotalCost(attempts)
+		latencies = append(latencies, latency)
+		if slo := latencySLO(req); slo > 0 && latency > slo {
+			r.SLOViolations++
+		}
+	}
+
+	if r.Requests > 0 {
+		r.MeanCostCents = r.CostCents / float64(r.Requests)
+	}
+	if served > 0 {
+		r.Accuracy /= float64(served)
+		for tier := range r.TierMix {
+			r.TierMix[tier] /= float64(served)
+		}
+	}
+	r.P50LatencyMS = percentile(latencies, 0.50)
+	r.P99LatencyMS = percentile(latencies, 0.99)
+	return r, nil
+}
+
+func latencySLO(req decision.Request) int {
+	if req.Policy != nil && req.Policy.LatencySLOMS > 0 {
+		return req.Policy.LatencySLOMS
+	}
+	return req.MaxLatencyMS
+}
+
+func percentile(values []int, q float64) int {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := make([]int, len(values))
+	copy(sorted, values)
+	sort.Ints(sorted)
+	i := int(q*float64(len(sorted))+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
@@ -0,0 +1,108 @@
+package simulation
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cost-aware-ml/pkg/decision"
+)
+
+const trace = `{"request_id": "a", "tenant_id": "t1", "input": "short", "budget": 10}
+{"request_id": "b", "tenant_id": "t1", "input": "twenty characters in", "budget": 10}
+
+{"request_id": "c", "tenant_id": "t1", "input": "x", "budget": 10, "max_latency_ms": 20, "outcomes": {"tier0": {"confidence": 0.3, "latency_ms": 30, "correct": true}}}
+`
+
+func TestReadTrace(t *testing.T) {
+	reqs, err := ReadTrace(strings.NewReader(trace))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reqs) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(reqs))
+	}
+	if o := reqs[2].Outcomes[decision.Tier0]; o.Confidence != 0.3 || o.Correct == nil || !*o.Correct {
+		t.Errorf("unexpected recorded outcome %+v", o)
+	}
+
+	if _, err := ReadTrace(strings.NewReader("{not json}\n")); err == nil {
+		t.Error("expected an error for a malformed line")
+	}
+	if _, err := ReadTrace(strings.NewReader(`{"request_id": "x", "title": "not a request"}` + "\n")); err == nil {
+		t.Error("expected an error for a record without input")
+	}
+}
+
+func TestRun(t *testing.T) {
+	reqs, _ := ReadTrace(strings.NewReader(trace))
+	worker := Recorded{Fallback: NewSynthetic(DefaultProfiles(), 1)}
+
+	r, err := Run(Config{Name: "default"}, reqs, worker)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a: tier0 answers 0.87 and is kept. b: tier0 answers 0.72 < 0.75 and
+	// escalates to tier1, which answers 0.88. c: the recorded 0.3 would
+	// escalate, but the 20ms SLO rules out tier1.
+	if r.Requests != 3 || r.Failed != 0 || r.Escalations != 1 {
+		t.Fatalf("unexpected result %+v", r)
+	}
+	if r.CostCents != 0.5+0.5+2.0+0.5 {
+		t.Errorf("expected cost 3.5, got %v", r.CostCents)
+	}
+	if r.Reasons["latency_slo_violation"] != 1 || r.SLOViolations != 1 {
+		t.Errorf("expected one SLO-limited decision and one violation, got %v / %d", r.Reasons, r.SLOViolations)
+	}
+	if want := (0.87 + 0.88 + 1.0) / 3; r.Accuracy < want-1e-9 || r.Accuracy > want+1e-9 {
+		t.Errorf("expected accuracy %.3f, got %.3f", want, r.Accuracy)
+	}
+
+	r, err = Run(Config{Name: "tier0 only", Policy: &decision.Policy{AllowedTiers: []decision.Tier{decision.Tier0}, MaxEscalations: new(int)}}, reqs, worker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.TierMix[decision.Tier0] != 1 || r.Escalations != 0 || r.MeanCostCents != 0.5 {
+		t.Errorf("expected everything served by tier0, got %+v", r)
+	}
+}
+
+func TestSweep(t *testing.T) {
+	reqs, _ := ReadTrace(strings.NewReader(trace))
+	grid := Grid{Thresholds: []float64{0.5, 0.8, 0.99}, ErrorRateCutoffs: []float64{0.1}}
+	points, err := Sweep(Config{Name: "sweep"}, reqs, grid, func() Worker { return NewSynthetic(DefaultProfiles(), 1) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	// tier0 and tier1 are swept; tier2 tops the cascade.
+	if len(points) != 9 {
+		t.Fatalf("expected 9 points, got %d", len(points))
+	}
+	for _, p := range points {
+		if len(p.Thresholds) != 2 || p.MaxErrorRate == nil || *p.MaxErrorRate != 0.1 {
+			t.Fatalf("unexpected point %+v", p)
+		}
+	}
+
+	frontier := ParetoFrontier(points)
+	if len(frontier) == 0 || len(frontier) > len(points) {
+		t.Fatalf("unexpected frontier size %d", len(frontier))
+	}
+	for _, p := range frontier {
+		for _, q := range points {
+			if dominates(q.Result, p.Result) {
+				t.Errorf("frontier point %v is dominated by %v", p.Thresholds, q.Thresholds)
+			}
+		}
+	}
+	// Never escalating is the cheapest configuration and must survive.
+	cheapest := false
+	for _, p := range frontier {
+		if p.Thresholds[decision.Tier0] == 0.5 && p.Result.MeanCostCents == 0.5 {
+			cheapest = true
+		}
+	}
+	if !cheapest {
+		t.Errorf("expected the never-escalate point on the frontier, got %+v", frontier)
+	}
+}
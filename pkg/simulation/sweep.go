@@ -0,0 +1,133 @@
+package simulation
+
+import "github.com/cost-aware-ml/pkg/decision"
+
+// Grid lists the values a sweep tries. Thresholds are tried independently
+// for every tier below the top of the cascade; an empty list keeps the
+// configured value.
+type Grid struct {
+	Thresholds       []float64
+	DefaultBudgets   []float64
+	ErrorRateCutoffs []float64
+}
+
+// Point is one grid assignment and its simulated result.
+type Point struct {
+	Thresholds    map[decision.Tier]float64 `json:"thresholds"`
+	DefaultBudget float64                   `json:"default_budget"`
+	MaxErrorRate  *float64                  `json:"max_error_rate,omitempty"`
+	Result        Result                    `json:"result"`
+}
+
+// Sweep simulates base under every grid assignment. Sweeping thresholds
+// drops the policy's conf_thresholds so the tier defaults take effect.
+// newWorker is called per assignment so every point sees the same draws.
+func Sweep(base Config, trace []TraceRequest, grid Grid, newWorker func() Worker) ([]Point, error) {
+	tiers := base.Tiers
+	if len(tiers) == 0 {
+		tiers = decision.DefaultTiers()
+	}
+	cascade := decision.NewEngineWithTiers(tiers).Cascade(base.Policy)
+
+	swept := make([]decision.Tier, 0, len(cascade))
+	if len(grid.Thresholds) > 0 && len(cascade) > 1 {
+		for _, t := range cascade[:len(cascade)-1] {
+			swept = append(swept, t.Name)
+		}
+	}
+	budgets := grid.DefaultBudgets
+	if len(budgets) == 0 {
+		budgets = []float64{base.DefaultBudget}
+	}
+	cutoffs := []*float64{nil}
+	if len(grid.ErrorRateCutoffs) > 0 {
+		cutoffs = cutoffs[:0]
+		for i := range grid.ErrorRateCutoffs {
+			cutoffs = append(cutoffs, &grid.ErrorRateCutoffs[i])
+		}
+	}
+
+	var points []Point
+	var err error
+	assign := make(map[decision.Tier]float64, len(swept))
+	var walk func(i int)
+	walk = func(i int) {
+		if err != nil {
+			return
+		}
+		if i < len(swept) {
+			for _, v := range grid.Thresholds {
+				assign[swept[i]] = v
+				walk(i + 1)
+			}
+			return
+		}
+		for _, budget := range budgets {
+			for _, cutoff := range cutoffs {
+				var p Point
+				p, err = runPoint(base, tiers, assign, budget, cutoff, trace, newWorker())
+				if err != nil {
+					return
+				}
+				points = append(points, p)
+			}
+		}
+	}
+	walk(0)
+	return points, err
+}
+
+func runPoint(base Config, tiers []decision.TierConfig, thresholds map[decision.Tier]float64, budget float64, cutoff *float64, trace []TraceRequest, worker Worker) (Point, error) {
+	c := base
+	c.DefaultBudget = budget
+	c.Tiers = make([]decision.TierConfig, len(tiers))
+	copy(c.Tiers, tiers)
+	for i := range c.Tiers {
+		if v, ok := thresholds[c.Tiers[i].Name]; ok {
+			c.Tiers[i].DefaultConfThreshold = v
+		}
+		if cutoff != nil {
+			c.Tiers[i].MaxErrorRate = *cutoff
+		}
+	}
+	if len(thresholds) > 0 && base.Policy != nil {
+		policy := *base.Policy
+		policy.ConfThresholds = nil
+		c.Policy = &policy
+	}
+
+	p := Point{Thresholds: make(map[decision.Tier]float64, len(thresholds)), DefaultBudget: budget, MaxErrorRate: cutoff}
+	for tier, v := range thresholds {
+		p.Thresholds[tier] = v
+	}
+	var err error
+	p.Result, err = Run(c, trace, worker)
+	return p, err
+}
+
+// dominates reports whether a is at least as good as b on cost, accuracy
+// and p99 latency and strictly better on one.
+func dominates(a, b Result) bool {
+	if a.MeanCostCents > b.MeanCostCents || a.Accuracy < b.Accuracy || a.P99LatencyMS > b.P99LatencyMS {
+		return false
+	}
+	return a.MeanCostCents < b.MeanCostCents || a.Accuracy > b.Accuracy || a.P99LatencyMS < b.P99LatencyMS
+}
+
+// ParetoFrontier keeps the points no other point dominates, in input order.
+func ParetoFrontier(points []Point) []Point {
+	var frontier []Point
+	for i, p := range points {
+		dominated := false
+		for j, q := range points {
+			if i != j && dominates(q.Result, p.Result) {
+				dominated = true
+				break
+			}
+		}
+		if !dominated {
+			frontier = append(frontier, p)
+		}
+	}
+	return frontier
+}
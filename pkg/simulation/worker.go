@@ -0,0 +1,148 @@
+package simulation
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"os"
+
+	"github.com/cost-aware-ml/pkg/decision"
+)
+
+// Response is what a simulated tier call returns. Accuracy is the
+// probability the answer is correct.
+type Response struct {
+	Confidence float64
+	LatencyMS  int
+	Accuracy   float64
+	Failed     bool
+}
+
+// Worker stands in for the tier workers.
+type Worker interface {
+	Call(tier decision.TierConfig, req TraceRequest) Response
+}
+
+// TelemetrySource is implemented by workers that can report the telemetry
+// the engine would observe for them.
+type TelemetrySource interface {
+	Telemetry() decision.Telemetry
+}
+
+// Profile describes a synthetic tier. Confidence is keyed by
+// decision.InputSizeBucket, matching how the workers score input length.
+type Profile struct {
+	Confidence       map[string]float64 `json:"confidence"`
+	ConfidenceJitter float64            `json:"confidence_jitter,omitempty"`
+	LatencyMS        int                `json:"latency_ms"`
+	LatencyJitterMS  int                `json:"latency_jitter_ms,omitempty"`
+	ErrorRate        float64            `json:"error_rate,omitempty"`
+}
+
+// DefaultProfiles mirrors services/workers.
+func DefaultProfiles() map[decision.Tier]Profile {
+	return map[decision.Tier]Profile{
+		decision.Tier0: {Confidence: map[string]float64{"lt10": 0.87, "lt50": 0.72, "lt100": 0.62, "ge100": 0.52}, LatencyMS: 15},
+		decision.Tier1: {Confidence: map[string]float64{"lt10": 0.96, "lt50": 0.88, "lt100": 0.78, "ge100": 0.73}, LatencyMS: 85},
+		decision.Tier2: {Confidence: map[string]float64{"lt10": 0.98, "lt50": 0.96, "lt100": 0.91, "ge100": 0.90}, LatencyMS: 250},
+	}
+}
+
+// LoadProfiles reads profiles keyed by tier from a JSON file; an empty path
+// returns DefaultProfiles.
+func LoadProfiles(path string) (map[decision.Tier]Profile, error) {
+	if path == "" {
+		return DefaultProfiles(), nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	profiles := make(map[decision.Tier]Profile)
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, err
+	}
+	return profiles, nil
+}
+
+// Synthetic draws responses from per-tier profiles and treats confidence as
+// calibrated. Tiers without a profile answer at their expected accuracy and
+// timeout.
+type Synthetic struct {
+	Profiles map[decision.Tier]Profile
+	rng      *rand.Rand
+}
+
+func NewSynthetic(profiles map[decision.Tier]Profile, seed int64) *Synthetic {
+	return &Synthetic{Profiles: profiles, rng: rand.New(rand.NewSource(seed))}
+}
+
+func (s *Synthetic) Call(tier decision.TierConfig, req TraceRequest) Response {
+	p, ok := s.Profiles[tier.Name]
+	if !ok {
+		conf := tier.ExpectedAccuracy
+		if conf == 0 {
+			conf = tier.DefaultConfThreshold
+		}
+		return Response{Confidence: conf, LatencyMS: tier.TimeoutMS, Accuracy: conf}
+	}
+	if p.ErrorRate > 0 && s.rng.Float64() < p.ErrorRate {
+		return Response{LatencyMS: p.LatencyMS, Failed: true}
+	}
+
+	conf := p.Confidence[decision.InputSizeBucket(req.Input)]
+	if p.ConfidenceJitter > 0 {
+		conf += s.rng.NormFloat64() * p.ConfidenceJitter
+	}
+	conf = math.Max(0, math.Min(1, conf))
+
+	latency := p.LatencyMS
+	if p.LatencyJitterMS > 0 {
+		latency += s.rng.Intn(2*p.LatencyJitterMS+1) - p.LatencyJitterMS
+	}
+	if latency < 0 {
+		latency = 0
+	}
+	return Response{Confidence: conf, LatencyMS: latency, Accuracy: conf}
+}
+
+// Telemetry reports each profile's error rate and worst-case latency.
+func (s *Synthetic) Telemetry() decision.Telemetry {
+	t := decision.Telemetry{
+		P99LatencyMS: make(map[decision.Tier]int),
+		ErrorRate:    make(map[decision.Tier]float64),
+	}
+	for tier, p := range s.Profiles {
+		t.P99LatencyMS[tier] = p.LatencyMS + p.LatencyJitterMS
+		t.ErrorRate[tier] = p.ErrorRate
+	}
+	return t
+}
+
+// Recorded replays outcomes recorded in the trace and falls back to another
+// worker for tiers a request has no recording for.
+type Recorded struct {
+	Fallback Worker
+}
+
+func (r Recorded) Call(tier decision.TierConfig, req TraceRequest) Response {
+	o, ok := req.Outcomes[tier.Name]
+	if !ok {
+		return r.Fallback.Call(tier, req)
+	}
+	resp := Response{Confidence: o.Confidence, LatencyMS: o.LatencyMS, Accuracy: o.Confidence, Failed: o.Error}
+	if o.Correct != nil {
+		resp.Accuracy = 0
+		if *o.Correct {
+			resp.Accuracy = 1
+		}
+	}
+	return resp
+}
+
+func (r Recorded) Telemetry() decision.Telemetry {
+	if src, ok := r.Fallback.(TelemetrySource); ok {
+		return src.Telemetry()
+	}
+	return decision.Telemetry{}
+}
@@ -0,0 +1,123 @@
+package spend
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Ledger accumulates what each tenant actually spent per billing month and
+// holds reservations for requests that are still in flight.
+type Ledger struct {
+	db  *sql.DB
+	ttl time.Duration
+}
+
+func New(db *sql.DB, reservationTTL time.Duration) *Ledger {
+	return &Ledger{db: db, ttl: reservationTTL}
+}
+
+var ErrBudgetExhausted = &LedgerError{Message: "monthly budget exhausted"}
+
+type LedgerError struct {
+	Message string
+}
+
+func (e *LedgerError) Error() string {
+	return e.Message
+}
+
+// BillingMonth returns the first day of the UTC month containing t.
+func BillingMonth(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// Remaining returns limit minus settled spend and unexpired reservations.
+func (l *Ledger) Remaining(ctx context.Context, tenantID string, limit float64, at time.Time) (float64, error) {
+	var committed float64
+	err := l.db.QueryRowContext(ctx, `SELECT
+		COALESCE((SELECT spent_cents FROM tenant_spend WHERE tenant_id = $1 AND billing_month = $2), 0) +
+		COALESCE((SELECT SUM(amount_cents) FROM budget_reservations WHERE tenant_id = $1 AND billing_month = $2 AND expires_at > NOW()), 0)`,
+		tenantID, BillingMonth(at)).Scan(&committed)
+	if err != nil {
+		return 0, err
+	}
+	return limit - committed, nil
+}
+
+// Reserve adds cents to the reservation held by reservationID, failing with
+// ErrBudgetExhausted if that would take the tenant past limit. The tenant's
+// ledger row is locked for the duration so concurrent requests serialize.
+func (l *Ledger) Reserve(ctx context.Context, tenantID, reservationID, requestID string, cents, limit float64, at time.Time) error {
+	month := BillingMonth(at)
+
+	tx, err := l.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO tenant_spend (tenant_id, billing_month) VALUES ($1, $2)
+		ON CONFLICT (tenant_id, billing_month) DO NOTHING`, tenantID, month); err != nil {
+		return err
+	}
+
+	var spent float64
+	if err := tx.QueryRowContext(ctx, `SELECT spent_cents FROM tenant_spend
+		WHERE tenant_id = $1 AND billing_month = $2 FOR UPDATE`, tenantID, month).Scan(&spent); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM budget_reservations
+		WHERE tenant_id = $1 AND expires_at <= NOW()`, tenantID); err != nil {
+		return err
+	}
+
+	var reserved float64
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(SUM(amount_cents), 0) FROM budget_reservations
+		WHERE tenant_id = $1 AND billing_month = $2`, tenantID, month).Scan(&reserved); err != nil {
+		return err
+	}
+
+	if spent+reserved+cents > limit {
+		return ErrBudgetExhausted
+	}
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO budget_reservations (reservation_id, request_id, tenant_id, billing_month, amount_cents, expires_at)
+		VALUES ($1, $2, $3, $4, $5, NOW() + $6 * INTERVAL '1 millisecond')
+		ON CONFLICT (reservation_id) DO UPDATE SET
+			amount_cents = budget_reservations.amount_cents + EXCLUDED.amount_cents,
+			expires_at = EXCLUDED.expires_at`, reservationID, requestID, tenantID, month, cents, l.ttl.Milliseconds()); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Settle drops any reservation held by reservationID and charges the tenant
+// the actual cost of the request.
+func (l *Ledger) Settle(ctx context.Context, tenantID, reservationID string, cents float64, at time.Time) error {
+	tx, err := l.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM budget_reservations WHERE reservation_id = $1`, reservationID); err != nil {
+		return err
+	}
+
+	if cents > 0 {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO tenant_spend (tenant_id, billing_month, spent_cents, request_count)
+			VALUES ($1, $2, $3, 1)
+			ON CONFLICT (tenant_id, billing_month) DO UPDATE SET
+				spent_cents = tenant_spend.spent_cents + EXCLUDED.spent_cents,
+				request_count = tenant_spend.request_count + 1,
+				updated_at = NOW()`, tenantID, BillingMonth(at), cents); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
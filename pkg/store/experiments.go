@@ -0,0 +1,72 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// StoredExperiment is a row of the experiments table; Definition is parsed
+// by the experiment package.
+type StoredExperiment struct {
+	Name       string
+	Definition []byte
+	Enabled    bool
+	CreatedAt  time.Time
+}
+
+// ListExperiments returns the enabled experiments, oldest first, which is
+// the order requests are offered to them in.
+func (s *Store) ListExperiments(ctx context.Context) ([]StoredExperiment, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT name, definition_json, enabled, created_at FROM experiments
+		WHERE enabled ORDER BY created_at, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var experiments []StoredExperiment
+	for rows.Next() {
+		var e StoredExperiment
+		if err := rows.Scan(&e.Name, &e.Definition, &e.Enabled, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		experiments = append(experiments, e)
+	}
+	return experiments, rows.Err()
+}
+
+// LoadExperiment returns the experiment called name, enabled or not, or nil
+// when there is none.
+func (s *Store) LoadExperiment(ctx context.Context, name string) (*StoredExperiment, error) {
+	e := StoredExperiment{Name: name}
+	err := s.db.QueryRowContext(ctx, `SELECT definition_json, enabled, created_at FROM experiments WHERE name = $1`, name).
+		Scan(&e.Definition, &e.Enabled, &e.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// SaveExperiment creates or replaces the experiment called name.
+func (s *Store) SaveExperiment(ctx context.Context, name string, definition []byte, enabled bool) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO experiments (name, definition_json, enabled) VALUES ($1, $2, $3)
+		ON CONFLICT (name) DO UPDATE SET definition_json = EXCLUDED.definition_json, enabled = EXCLUDED.enabled`,
+		name, definition, enabled)
+	return err
+}
+
+// LoadExperimentTraces returns the requests enrolled in experiment since
+// the given time.
+func (s *Store) LoadExperimentTraces(ctx context.Context, experiment string, since time.Time) ([]RequestTrace, error) {
+	rows, err := s.db.QueryContext(ctx, traceColumns+`
+		WHERE r.experiment = $1 AND r.created_at >= $2 AND r.cost_breakdown IS NOT NULL
+		ORDER BY r.created_at`, experiment, since)
+	if err != nil {
+		return nil, err
+	}
+	return scanTraces(rows)
+}
@@ -0,0 +1,91 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+var (
+	ErrNotFound       = &StoreError{Message: "not found"}
+	ErrTenantMismatch = &StoreError{Message: "request belongs to another tenant"}
+)
+
+type StoreError struct {
+	Message string
+}
+
+func (e *StoreError) Error() string {
+	return e.Message
+}
+
+// Feedback is ground truth for an answered request. When Correct is nil the
+// answer is graded by comparing Label with the stored result. A non-empty
+// TenantID must match the tenant the request was served for.
+type Feedback struct {
+	RequestID string
+	TenantID  string
+	Label     string
+	Correct   *bool
+}
+
+// RecordFeedback grades and stores feedback, replacing any earlier feedback
+// for the same request. It returns the graded outcome, or ErrTenantMismatch
+// without storing anything when fb names another tenant.
+func (s *Store) RecordFeedback(ctx context.Context, fb Feedback) (LabeledOutcome, error) {
+	o := LabeledOutcome{RequestID: fb.RequestID}
+	var result string
+	err := s.db.QueryRowContext(ctx, `SELECT COALESCE(tenant_id, ''), tier, COALESCE(confidence, 0),
+		COALESCE(result, ''), created_at FROM inference_requests WHERE request_id = $1`, fb.RequestID).
+		Scan(&o.TenantID, &o.Tier, &o.Confidence, &result, &o.CreatedAt)
+	if err == sql.ErrNoRows {
+		return o, ErrNotFound
+	}
+	if err != nil {
+		return o, err
+	}
+	if fb.TenantID != "" && fb.TenantID != o.TenantID {
+		return o, ErrTenantMismatch
+	}
+
+	if fb.Correct != nil {
+		o.Correct = *fb.Correct
+	} else {
+		o.Correct = fb.Label == result
+	}
+
+	_, err = s.db.ExecContext(ctx, `INSERT INTO feedback (request_id, label, correct) VALUES ($1, NULLIF($2, ''), $3)
+		ON CONFLICT (request_id) DO UPDATE SET label = EXCLUDED.label, correct = EXCLUDED.correct, created_at = NOW()`,
+		fb.RequestID, fb.Label, o.Correct)
+	return o, err
+}
+
+type AccuracyStat struct {
+	TenantID string
+	Tier     string
+	Labeled  int
+	Correct  int
+}
+
+// AccuracyStats counts labeled and correct answers per tenant and tier for
+// requests created since the given time.
+func (s *Store) AccuracyStats(ctx context.Context, since time.Time) ([]AccuracyStat, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT COALESCE(r.tenant_id, ''), r.tier, COUNT(*),
+		COUNT(*) FILTER (WHERE f.correct)
+		FROM inference_requests r JOIN feedback f ON f.request_id = r.request_id
+		WHERE r.created_at >= $1 GROUP BY 1, 2 ORDER BY 1, 2`, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []AccuracyStat
+	for rows.Next() {
+		var st AccuracyStat
+		if err := rows.Scan(&st.TenantID, &st.Tier, &st.Labeled, &st.Correct); err != nil {
+			return nil, err
+		}
+		stats = append(stats, st)
+	}
+	return stats, rows.Err()
+}
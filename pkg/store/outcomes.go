@@ -0,0 +1,94 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/cost-aware-ml/pkg/decision"
+)
+
+// LabeledOutcome is an answered request joined with its feedback.
+type LabeledOutcome struct {
+	RequestID  string
+	TenantID   string
+	Tier       string
+	Confidence float64
+	Correct    bool
+	CreatedAt  time.Time
+}
+
+// LabeledAttempt is one successful tier call of a labeled request, graded
+// against the request's feedback.
+type LabeledAttempt struct {
+	RequestID  string
+	Tier       string
+	Confidence float64
+	Correct    bool
+}
+
+// LoadLabeledAttempts grades the tier calls of every request created since
+// the given time that has feedback, so each tier is judged on the answers
+// it gave and not only on those that were kept.
+func (s *Store) LoadLabeledAttempts(ctx context.Context, since time.Time) ([]LabeledAttempt, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT r.request_id, r.tier, COALESCE(r.confidence, 0), r.cost_breakdown,
+		f.correct, COALESCE(f.label, '')
+		FROM inference_requests r JOIN feedback f ON f.request_id = r.request_id
+		WHERE r.created_at >= $1 ORDER BY r.created_at`, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var graded []LabeledAttempt
+	for rows.Next() {
+		var requestID, tier, label string
+		var confidence float64
+		var breakdown []byte
+		var correct bool
+		if err := rows.Scan(&requestID, &tier, &confidence, &breakdown, &correct, &label); err != nil {
+			return nil, err
+		}
+		var attempts []decision.Attempt
+		if breakdown != nil {
+			json.Unmarshal(breakdown, &attempts)
+		}
+		graded = append(graded, GradeAttempts(requestID, tier, confidence, attempts, correct, label)...)
+	}
+	return graded, rows.Err()
+}
+
+// GradeAttempts grades the successful attempts of a request served from
+// tier with the given confidence. An attempt that logged its answer is
+// graded against the feedback label; the served one otherwise takes the
+// feedback's verdict, and other attempts are left out. Requests logged
+// without attempts are graded on the served answer alone.
+func GradeAttempts(requestID, tier string, confidence float64, attempts []decision.Attempt, correct bool, label string) []LabeledAttempt {
+	served := -1
+	for i, a := range attempts {
+		if string(a.Tier) == tier && a.Outcome == decision.OutcomeOK {
+			served = i
+		}
+	}
+	if served < 0 {
+		return []LabeledAttempt{{RequestID: requestID, Tier: tier, Confidence: confidence, Correct: correct}}
+	}
+
+	var graded []LabeledAttempt
+	for i, a := range attempts {
+		if a.Outcome != decision.OutcomeOK {
+			continue
+		}
+		g := LabeledAttempt{RequestID: requestID, Tier: string(a.Tier), Confidence: a.Confidence}
+		switch {
+		case label != "" && a.Result != "":
+			g.Correct = a.Result == label
+		case i == served:
+			g.Correct = correct
+		default:
+			continue
+		}
+		graded = append(graded, g)
+	}
+	return graded
+}
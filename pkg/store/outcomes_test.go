@@ -0,0 +1,31 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/cost-aware-ml/pkg/decision"
+)
+
+func TestGradeAttempts(t *testing.T) {
+	attempts := []decision.Attempt{
+		{Tier: decision.Tier0, Outcome: decision.OutcomeOK, Confidence: 0.3, Result: "cat"},
+		{Tier: decision.Tier1, Outcome: decision.OutcomeError},
+		{Tier: decision.Tier2, Outcome: decision.OutcomeOK, Confidence: 0.9, Result: "dog"},
+	}
+
+	graded := GradeAttempts("r1", "tier2", 0.9, attempts, true, "dog")
+	if len(graded) != 2 || graded[0].Tier != "tier0" || graded[0].Correct || !graded[1].Correct {
+		t.Errorf("expected tier0 graded wrong and tier2 right against the label, got %+v", graded)
+	}
+
+	// Without a label only the served answer can be graded.
+	graded = GradeAttempts("r1", "tier2", 0.9, attempts, false, "")
+	if len(graded) != 1 || graded[0].Tier != "tier2" || graded[0].Correct {
+		t.Errorf("expected only tier2 graded, wrong, got %+v", graded)
+	}
+
+	graded = GradeAttempts("r1", "tier0", 0.4, nil, true, "")
+	if len(graded) != 1 || graded[0].Confidence != 0.4 || !graded[0].Correct {
+		t.Errorf("expected a request without attempts to be graded on its answer, got %+v", graded)
+	}
+}
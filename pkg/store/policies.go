@@ -0,0 +1,61 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/cost-aware-ml/pkg/decision"
+)
+
+// LoadPolicy returns the most recent policy for tenantID, or nil when the
+// tenant has none.
+func (s *Store) LoadPolicy(ctx context.Context, tenantID string) (*decision.Policy, error) {
+	var data []byte
+	err := s.db.QueryRowContext(ctx, `SELECT policy_json FROM policies
+		WHERE tenant_id = $1 AND policy_json IS NOT NULL
+		ORDER BY created_at DESC, id DESC LIMIT 1`, tenantID).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decision.ParsePolicy(data)
+}
+
+// SavePolicy stores p as the tenant's newest policy.
+func (s *Store) SavePolicy(ctx context.Context, tenantID string, p *decision.Policy) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `INSERT INTO policies (tenant_id, policy_json) VALUES ($1, $2)`, tenantID, data)
+	return err
+}
+
+// ListPolicies returns the newest policy of every tenant that has one.
+// Policies that fail to parse are skipped.
+func (s *Store) ListPolicies(ctx context.Context) (map[string]*decision.Policy, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT DISTINCT ON (tenant_id) tenant_id, policy_json FROM policies
+		WHERE policy_json IS NOT NULL ORDER BY tenant_id, created_at DESC, id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	policies := make(map[string]*decision.Policy)
+	for rows.Next() {
+		var tenantID string
+		var data []byte
+		if err := rows.Scan(&tenantID, &data); err != nil {
+			return nil, err
+		}
+		p, err := decision.ParsePolicy(data)
+		if err != nil {
+			continue
+		}
+		policies[tenantID] = p
+	}
+	return policies, rows.Err()
+}
@@ -0,0 +1,13 @@
+package store
+
+import (
+	"database/sql"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) *Store {
+	return &Store{db: db}
+}
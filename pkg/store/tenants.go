@@ -0,0 +1,29 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+)
+
+type Tenant struct {
+	TenantID                     string
+	Plan                         string
+	MonthlyBudgetCents           float64
+	PerRequestBudgetCentsDefault float64
+	SLOP99MS                     int
+}
+
+// LoadTenant returns nil when the tenant is not registered.
+func (s *Store) LoadTenant(ctx context.Context, tenantID string) (*Tenant, error) {
+	t := Tenant{TenantID: tenantID}
+	err := s.db.QueryRowContext(ctx, `SELECT plan, COALESCE(monthly_budget_cents, 0),
+		COALESCE(per_request_budget_cents_default, 0), COALESCE(slo_p99_ms, 0)
+		FROM tenants WHERE tenant_id = $1`, tenantID).Scan(&t.Plan, &t.MonthlyBudgetCents, &t.PerRequestBudgetCentsDefault, &t.SLOP99MS)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
@@ -0,0 +1,73 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/cost-aware-ml/pkg/decision"
+)
+
+// LoadTiers returns every row of the tiers table ordered from cheapest to
+// most expensive, which is the order the cascade escalates in.
+func (s *Store) LoadTiers(ctx context.Context) ([]decision.TierConfig, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT name, COALESCE(url, ''), base_cost_cents, timeout_ms,
+		COALESCE(max_concurrency, 0), COALESCE(default_conf_threshold, 0), COALESCE(max_error_rate, 0), COALESCE(expected_accuracy, 0), COALESCE(bill_failed_attempts, false), COALESCE(enabled, true),
+		COALESCE(cost_per_1k_tokens_cents, 0), COALESCE(cost_per_kb_cents, 0), COALESCE(cost_per_item_cents, 0),
+		COALESCE(latency_per_1k_tokens_ms, 0), COALESCE(latency_per_kb_ms, 0), COALESCE(latency_per_item_ms, 0)
+		FROM tiers ORDER BY base_cost_cents, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tiers []decision.TierConfig
+	for rows.Next() {
+		var t decision.TierConfig
+		var name string
+		if err := rows.Scan(&name, &t.URL, &t.BaseCostCents, &t.TimeoutMS, &t.MaxConcurrency, &t.DefaultConfThreshold, &t.MaxErrorRate, &t.ExpectedAccuracy, &t.BillFailedAttempts, &t.Enabled,
+			&t.Pricing.CostPer1KTokensCents, &t.Pricing.CostPerKBCents, &t.Pricing.CostPerItemCents,
+			&t.Pricing.LatencyPer1KTokensMS, &t.Pricing.LatencyPerKBMS, &t.Pricing.LatencyPerItemMS); err != nil {
+			return nil, err
+		}
+		t.Name = decision.Tier(name)
+		tiers = append(tiers, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(tiers) == 0 {
+		return nil, fmt.Errorf("tiers table is empty")
+	}
+	return tiers, nil
+}
+
+// LoadTiersOrDefault falls back to decision.DefaultTiers when the store is
+// unavailable so services can still start without Postgres.
+func LoadTiersOrDefault(ctx context.Context, s *Store) []decision.TierConfig {
+	if s == nil {
+		return decision.DefaultTiers()
+	}
+	tiers, err := s.LoadTiers(ctx)
+	if err != nil {
+		log.Printf("failed to load tiers: %v (using defaults)", err)
+		return decision.DefaultTiers()
+	}
+	return tiers
+}
+
+// UpdateTierThresholds sets default_conf_threshold for each named tier.
+func (s *Store) UpdateTierThresholds(ctx context.Context, thresholds map[decision.Tier]float64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for tier, threshold := range thresholds {
+		if _, err := tx.ExecContext(ctx, `UPDATE tiers SET default_conf_threshold = $1 WHERE name = $2`, threshold, string(tier)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
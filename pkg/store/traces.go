@@ -0,0 +1,94 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/cost-aware-ml/pkg/decision"
+)
+
+// RequestTrace is an answered request with every tier attempt it made and,
+// when feedback exists, whether the final answer was correct.
+type RequestTrace struct {
+	RequestID string
+	TenantID  string
+	Tier      string
+	Budget    float64
+	Attempts  []decision.Attempt
+	Correct   *bool
+	CreatedAt time.Time
+	// Strategy, Propensity and Context are what the routing policy logged
+	// when it picked EntryTier, which is empty for requests logged before
+	// it was recorded.
+	EntryTier  string
+	Strategy   string
+	Propensity float64
+	Context    string
+	LabeledAt  time.Time
+	Input      decision.InputFeatures
+	// Experiment and Arm are set for requests enrolled in an experiment.
+	Experiment string
+	Arm        string
+	// Unit is the tenant or user ID the request was assigned to its arm by.
+	Unit string
+}
+
+// LoadTraces returns requests created since the given time that recorded a
+// cost breakdown. An empty tenantID loads every tenant.
+func (s *Store) LoadTraces(ctx context.Context, tenantID string, since time.Time) ([]RequestTrace, error) {
+	rows, err := s.db.QueryContext(ctx, traceColumns+`
+		WHERE r.created_at >= $1 AND ($2 = '' OR r.tenant_id = $2) AND r.cost_breakdown IS NOT NULL
+		ORDER BY r.created_at`, since, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return scanTraces(rows)
+}
+
+// LoadLabeledTraces returns traces routed by strategy whose feedback was
+// recorded after the given time, oldest feedback first.
+func (s *Store) LoadLabeledTraces(ctx context.Context, strategy string, after time.Time) ([]RequestTrace, error) {
+	rows, err := s.db.QueryContext(ctx, traceColumns+`
+		WHERE f.created_at > $1 AND r.strategy = $2 AND r.cost_breakdown IS NOT NULL
+		ORDER BY f.created_at`, after, strategy)
+	if err != nil {
+		return nil, err
+	}
+	return scanTraces(rows)
+}
+
+const traceColumns = `SELECT r.request_id, COALESCE(r.tenant_id, ''), r.tier, COALESCE(r.budget, 0), r.cost_breakdown, f.correct, r.created_at,
+		COALESCE(r.strategy, ''), COALESCE(r.propensity, 1), COALESCE(r.bandit_context, ''), f.created_at,
+		COALESCE(r.input_tokens, 0), COALESCE(r.input_bytes, 0), COALESCE(r.input_items, 0),
+		COALESCE(r.experiment, ''), COALESCE(r.arm, ''), COALESCE(r.experiment_unit, r.tenant_id, ''),
+		COALESCE(r.entry_tier, '')
+		FROM inference_requests r LEFT JOIN feedback f ON f.request_id = r.request_id`
+
+func scanTraces(rows *sql.Rows) ([]RequestTrace, error) {
+	defer rows.Close()
+
+	var traces []RequestTrace
+	for rows.Next() {
+		var t RequestTrace
+		var breakdown []byte
+		var correct *bool
+		var labeledAt *time.Time
+		if err := rows.Scan(&t.RequestID, &t.TenantID, &t.Tier, &t.Budget, &breakdown, &correct, &t.CreatedAt,
+			&t.Strategy, &t.Propensity, &t.Context, &labeledAt,
+			&t.Input.Tokens, &t.Input.Bytes, &t.Input.Items,
+			&t.Experiment, &t.Arm, &t.Unit, &t.EntryTier); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(breakdown, &t.Attempts); err != nil {
+			continue
+		}
+		t.Correct = correct
+		if labeledAt != nil {
+			t.LabeledAt = *labeledAt
+		}
+		traces = append(traces, t)
+	}
+	return traces, rows.Err()
+}
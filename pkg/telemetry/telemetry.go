@@ -41,16 +41,22 @@ func NewCollector(prometheusURL string) *Collector {
 	}
 }
 
-func (c *Collector) CollectTelemetry(ctx context.Context) (decision.Telemetry, error) {
+func (c *Collector) CollectTelemetry(ctx context.Context, tiers []decision.Tier) (decision.Telemetry, error) {
 	telemetry := decision.Telemetry{
+		P95LatencyMS: make(map[decision.Tier]int),
 		P99LatencyMS: make(map[decision.Tier]int),
 		ErrorRate:    make(map[decision.Tier]float64),
 		QueueDepth:   make(map[decision.Tier]int),
 	}
 
-	for _, tier := range []decision.Tier{decision.Tier0, decision.Tier1, decision.Tier2} {
+	for _, tier := range tiers {
 		tierStr := string(tier)
 
+		p95Latency, err := c.queryP95CallLatency(ctx, tierStr)
+		if err == nil {
+			telemetry.P95LatencyMS[tier] = p95Latency
+		}
+
 		p99Latency, err := c.queryP99Latency(ctx, tierStr)
 		if err == nil {
 			telemetry.P99LatencyMS[tier] = p99Latency
@@ -79,6 +85,17 @@ func (c *Collector) queryP99Latency(ctx context.Context, tier string) (int, erro
 	return int(value), nil
 }
 
+// queryP95CallLatency is the P95 of the controlplane's calls to tier's
+// worker, which the controlplane waits out before hedging.
+func (c *Collector) queryP95CallLatency(ctx context.Context, tier string) (int, error) {
+	query := fmt.Sprintf(`histogram_quantile(0.95, sum by (le) (rate(controlplane_tier_call_duration_seconds_bucket{tier="%s"}[5m]))) * 1000`, tier)
+	value, err := c.queryPrometheus(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	return int(value), nil
+}
+
 func (c *Collector) queryErrorRate(ctx context.Context, tier string) (float64, error) {
 	query := fmt.Sprintf(`sum(rate(gateway_requests_total{status=~"error|worker_error|controlplane_error",tier="%s"}[5m])) / sum(rate(gateway_requests_total{tier="%s"}[5m]))`, tier, tier)
 	value, err := c.queryPrometheus(ctx, query)
@@ -91,8 +108,10 @@ func (c *Collector) queryErrorRate(ctx context.Context, tier string) (float64, e
 	return value, nil
 }
 
+// queryQueueDepth sums the calls waiting on or running in tier across
+// controlplane replicas.
 func (c *Collector) queryQueueDepth(ctx context.Context, tier string) (int, error) {
-	query := `gateway_queue_depth`
+	query := fmt.Sprintf(`sum(controlplane_tier_queue_depth{tier="%s"})`, tier)
 	value, err := c.queryPrometheus(ctx, query)
 	if err != nil {
 		return 0, err
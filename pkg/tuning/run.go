@@ -0,0 +1,106 @@
+package tuning
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cost-aware-ml/pkg/calibration"
+	"github.com/cost-aware-ml/pkg/decision"
+	"github.com/cost-aware-ml/pkg/store"
+)
+
+const (
+	ApplyTiers  = "tiers"
+	ApplyPolicy = "policy"
+)
+
+type Options struct {
+	TenantID          string
+	Target            float64
+	Since             time.Time
+	Step              float64
+	CalibrationMethod string
+}
+
+// Run tunes thresholds for one tenant from its logged cascades, estimating
+// answer accuracy with per-tier calibration maps fitted from all feedback.
+func Run(ctx context.Context, s *store.Store, engine *decision.Engine, policy *decision.Policy, opts Options) (Result, error) {
+	if opts.Step <= 0 {
+		opts.Step = 0.05
+	}
+	if opts.CalibrationMethod == "" {
+		opts.CalibrationMethod = calibration.MethodIsotonic
+	}
+
+	graded, err := s.LoadLabeledAttempts(ctx, opts.Since)
+	if err != nil {
+		return Result{}, err
+	}
+	samples := make(map[string][]calibration.Sample)
+	for _, a := range graded {
+		samples[a.Tier] = append(samples[a.Tier], calibration.Sample{Confidence: a.Confidence, Correct: a.Correct})
+	}
+	calibrator := calibration.NewCalibrator(opts.CalibrationMethod, 50)
+	if err := calibrator.Refit(samples); err != nil {
+		return Result{}, err
+	}
+
+	logged, err := s.LoadTraces(ctx, opts.TenantID, opts.Since)
+	if err != nil {
+		return Result{}, err
+	}
+	traces := make([]Trace, len(logged))
+	for i, t := range logged {
+		traces[i] = Trace{Attempts: t.Attempts, Input: t.Input}
+	}
+
+	acc := func(tier decision.Tier, confidence float64) float64 {
+		return calibrator.Calibrate(string(tier), confidence)
+	}
+	return Search(engine.Cascade(policy), traces, Grid(0.5, 0.99, opts.Step), opts.Target, acc), nil
+}
+
+// Apply writes thresholds to the tiers table or, for ApplyPolicy, to a new
+// policy row for tenantID that keeps the rest of policy unchanged. A policy
+// that already has the thresholds is left alone; it reports whether anything
+// was written.
+func Apply(ctx context.Context, s *store.Store, target, tenantID string, policy *decision.Policy, thresholds map[decision.Tier]float64) (bool, error) {
+	switch target {
+	case ApplyTiers:
+		return true, s.UpdateTierThresholds(ctx, thresholds)
+	case ApplyPolicy:
+		if tenantID == "" {
+			return false, fmt.Errorf("a tenant is required to apply thresholds to a policy")
+		}
+		updated, changed := withThresholds(policy, thresholds)
+		if !changed {
+			return false, nil
+		}
+		return true, s.SavePolicy(ctx, tenantID, updated)
+	default:
+		return false, fmt.Errorf("unknown apply target %q", target)
+	}
+}
+
+// withThresholds returns a copy of policy with thresholds merged into its
+// conf_thresholds, and whether that changed any of them.
+func withThresholds(policy *decision.Policy, thresholds map[decision.Tier]float64) (*decision.Policy, bool) {
+	updated := decision.Policy{}
+	if policy != nil {
+		updated = *policy
+	}
+	changed := false
+	merged := make(map[decision.Tier]float64)
+	for tier, v := range updated.ConfThresholds {
+		merged[tier] = v
+	}
+	for tier, v := range thresholds {
+		if old, ok := merged[tier]; !ok || old != v {
+			changed = true
+		}
+		merged[tier] = v
+	}
+	updated.ConfThresholds = merged
+	return &updated, changed
+}
@@ -0,0 +1,175 @@
+package tuning
+
+import (
+	"sort"
+
+	"github.com/cost-aware-ml/pkg/decision"
+)
+
+// Accuracy estimates the probability that tier's answer is correct given
+// its raw confidence, usually a calibration map.
+type Accuracy func(tier decision.Tier, confidence float64) float64
+
+// Candidate is one threshold assignment and its simulated mean accuracy and
+// cost per request over the traces.
+type Candidate struct {
+	Thresholds map[decision.Tier]float64 `json:"thresholds"`
+	Accuracy   float64                   `json:"accuracy"`
+	CostCents  float64                   `json:"cost_cents"`
+}
+
+// Trace is one logged cascade with the size of its input, which tier cost
+// scales with.
+type Trace struct {
+	Attempts []decision.Attempt
+	Input    decision.InputFeatures
+}
+
+type Result struct {
+	Target   float64     `json:"target_accuracy"`
+	Traces   int         `json:"traces"`
+	Best     *Candidate  `json:"best,omitempty"`
+	Frontier []Candidate `json:"frontier"`
+}
+
+// Simulate replays one logged cascade under thresholds. A tier whose
+// confidence was logged stops the cascade once its estimated accuracy
+// reaches the threshold; a tier the log never reached is assumed to answer
+// at its expected accuracy. ok is false when the trace did not start at the
+// entry tier and cannot be replayed.
+func Simulate(cascade []decision.TierConfig, trace Trace, thresholds map[decision.Tier]float64, acc Accuracy) (accuracy, cost float64, ok bool) {
+	observed := make(map[decision.Tier]float64)
+	for _, a := range trace.Attempts {
+		if a.Outcome == decision.OutcomeOK {
+			observed[a.Tier] = a.Confidence
+		}
+	}
+	if len(cascade) == 0 {
+		return 0, 0, false
+	}
+	if _, seen := observed[cascade[0].Name]; !seen {
+		return 0, 0, false
+	}
+
+	for i, t := range cascade {
+		// Priced for the input as decision.EstimateCost does.
+		cost += t.Cost(trace.Input)
+		conf, seen := observed[t.Name]
+		if !seen {
+			return expectedAccuracy(t), cost, true
+		}
+		p := acc(t.Name, conf)
+		if i == len(cascade)-1 || p >= thresholds[t.Name] {
+			return p, cost, true
+		}
+	}
+	return 0, cost, true
+}
+
+func expectedAccuracy(t decision.TierConfig) float64 {
+	if t.ExpectedAccuracy > 0 {
+		return t.ExpectedAccuracy
+	}
+	return t.DefaultConfThreshold
+}
+
+// Evaluate simulates every trace under thresholds and averages the result.
+func Evaluate(cascade []decision.TierConfig, traces []Trace, thresholds map[decision.Tier]float64, acc Accuracy) (Candidate, int) {
+	c := Candidate{Thresholds: thresholds}
+	n := 0
+	for _, trace := range traces {
+		a, cost, ok := Simulate(cascade, trace, thresholds, acc)
+		if !ok {
+			continue
+		}
+		c.Accuracy += a
+		c.CostCents += cost
+		n++
+	}
+	if n > 0 {
+		c.Accuracy /= float64(n)
+		c.CostCents /= float64(n)
+	}
+	return c, n
+}
+
+// Grid returns threshold values from lo to hi inclusive in steps of step.
+func Grid(lo, hi, step float64) []float64 {
+	var grid []float64
+	for i := 0; ; i++ {
+		v := lo + float64(i)*step
+		if v > hi+1e-9 {
+			break
+		}
+		grid = append(grid, v)
+	}
+	return grid
+}
+
+// Search tries every combination of grid values for every tier but the last
+// (whose answer is always kept) and returns the cheapest combination that
+// reaches target along with the cost/accuracy Pareto frontier.
+func Search(cascade []decision.TierConfig, traces []Trace, grid []float64, target float64, acc Accuracy) Result {
+	result := Result{Target: target}
+	if len(cascade) == 0 {
+		return result
+	}
+
+	var candidates []Candidate
+	free := cascade[:len(cascade)-1]
+	thresholds := make(map[decision.Tier]float64)
+	var walk func(i int)
+	walk = func(i int) {
+		if i == len(free) {
+			assigned := make(map[decision.Tier]float64, len(thresholds))
+			for k, v := range thresholds {
+				assigned[k] = v
+			}
+			c, n := Evaluate(cascade, traces, assigned, acc)
+			result.Traces = n
+			candidates = append(candidates, c)
+			return
+		}
+		for _, v := range grid {
+			thresholds[free[i].Name] = v
+			walk(i + 1)
+		}
+	}
+	walk(0)
+
+	for i := range candidates {
+		c := &candidates[i]
+		if c.Accuracy < target {
+			continue
+		}
+		if result.Best == nil || c.CostCents < result.Best.CostCents ||
+			(c.CostCents == result.Best.CostCents && c.Accuracy > result.Best.Accuracy) {
+			result.Best = c
+		}
+	}
+	result.Frontier = Frontier(candidates)
+	return result
+}
+
+// Frontier keeps the candidates no other candidate beats on both cost and
+// accuracy, cheapest first.
+func Frontier(candidates []Candidate) []Candidate {
+	sorted := make([]Candidate, len(candidates))
+	copy(sorted, candidates)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].CostCents != sorted[j].CostCents {
+			return sorted[i].CostCents < sorted[j].CostCents
+		}
+		return sorted[i].Accuracy > sorted[j].Accuracy
+	})
+
+	var frontier []Candidate
+	best := -1.0
+	for _, c := range sorted {
+		if c.Accuracy > best {
+			frontier = append(frontier, c)
+			best = c.Accuracy
+		}
+	}
+	return frontier
+}
@@ -0,0 +1,74 @@
+package tuning
+
+import (
+	"testing"
+
+	"github.com/cost-aware-ml/pkg/decision"
+)
+
+func TestSearch(t *testing.T) {
+	cascade := decision.DefaultTiers()
+	identity := func(_ decision.Tier, confidence float64) float64 { return confidence }
+
+	var traces []Trace
+	for _, conf := range []float64{0.55, 0.65, 0.75, 0.85, 0.95} {
+		traces = append(traces, Trace{Attempts: []decision.Attempt{
+			{Tier: decision.Tier0, Outcome: decision.OutcomeOK, Confidence: conf},
+			{Tier: decision.Tier1, Outcome: decision.OutcomeOK, Confidence: 0.9},
+		}})
+	}
+
+	result := Search(cascade, traces, Grid(0.5, 0.95, 0.05), 0.85, identity)
+	if result.Traces != len(traces) {
+		t.Fatalf("expected %d traces, got %d", len(traces), result.Traces)
+	}
+	if result.Best == nil {
+		t.Fatal("expected thresholds meeting the target")
+	}
+	if result.Best.Accuracy < 0.85 {
+		t.Errorf("expected accuracy >= 0.85, got %v", result.Best.Accuracy)
+	}
+	for _, c := range result.Frontier {
+		if c.Accuracy >= 0.85 && c.CostCents < result.Best.CostCents {
+			t.Errorf("frontier point %+v is cheaper than best %+v", c, *result.Best)
+		}
+	}
+	for i := 1; i < len(result.Frontier); i++ {
+		prev, cur := result.Frontier[i-1], result.Frontier[i]
+		if cur.CostCents < prev.CostCents || cur.Accuracy <= prev.Accuracy {
+			t.Errorf("frontier not monotone at %d: %+v then %+v", i, prev, cur)
+		}
+	}
+
+	if result := Search(cascade, traces, Grid(0.5, 0.95, 0.05), 0.999, identity); result.Best != nil {
+		t.Errorf("expected unreachable target, got %+v", *result.Best)
+	}
+}
+
+func TestSimulatePricesInput(t *testing.T) {
+	cascade := decision.DefaultTiers()
+	cascade[0].Pricing.CostPer1KTokensCents = 1.0
+	identity := func(_ decision.Tier, confidence float64) float64 { return confidence }
+	trace := Trace{
+		Attempts: []decision.Attempt{{Tier: decision.Tier0, Outcome: decision.OutcomeOK, Confidence: 0.9}},
+		Input:    decision.InputFeatures{Tokens: 2000},
+	}
+	_, cost, ok := Simulate(cascade, trace, map[decision.Tier]float64{decision.Tier0: 0.8}, identity)
+	if want := cascade[0].BaseCostCents + 2.0; !ok || cost != want {
+		t.Errorf("expected tier0 priced for 2000 tokens at %.2f, got %.2f", want, cost)
+	}
+}
+
+func TestWithThresholds(t *testing.T) {
+	policy := &decision.Policy{ConfThresholds: map[decision.Tier]float64{decision.Tier0: 0.7, decision.Tier1: 0.8}}
+	if _, changed := withThresholds(policy, map[decision.Tier]float64{decision.Tier0: 0.7}); changed {
+		t.Error("expected thresholds the policy already has not to count as a change")
+	}
+	updated, changed := withThresholds(policy, map[decision.Tier]float64{decision.Tier0: 0.75})
+	if !changed || updated.ConfThresholds[decision.Tier0] != 0.75 || updated.ConfThresholds[decision.Tier1] != 0.8 {
+		t.Errorf("expected tier0 to be updated and tier1 kept, got %v", updated.ConfThresholds)
+	}
+	if policy.ConfThresholds[decision.Tier0] != 0.7 {
+		t.Error("expected the original policy to be left alone")
+	}
+}
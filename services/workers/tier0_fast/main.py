@@ -2,6 +2,7 @@ import os
 import time
 import hashlib
 from fastapi import FastAPI, Request
+from fastapi.responses import JSONResponse
 
 app = FastAPI()
 
@@ -26,6 +27,12 @@ def health():
 
 @app.post("/infer")
 async def infer(request: Request):
+    # X-Deadline-Ms is what is left of the caller's latency budget; an
+    # answer that cannot arrive in time is not worth computing.
+    deadline_ms = request.headers.get("x-deadline-ms")
+    if deadline_ms is not None and deadline_ms.isdigit() and int(deadline_ms) < 15:
+        return JSONResponse(status_code=504, content={"error": "deadline exceeded"})
+
     data = await request.json()
     input_data = data.get("input", "")
     
@@ -2,6 +2,8 @@ package integration
 
 import (
 	"bytes"
+	"crypto/md5"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -149,3 +151,85 @@ func TestEscalationFlow(t *testing.T) {
 	}
 }
 
+// TestInputReachesWorker checks the request input travels gateway →
+// controlplane → worker: every worker answers
+// prediction_<tier>_<first 8 hex digits of md5(input)>.
+func TestInputReachesWorker(t *testing.T) {
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	for _, input := range []string{"the first input", "a different input"} {
+		req := map[string]interface{}{
+			"request_id": fmt.Sprintf("test-input-%d", time.Now().UnixNano()),
+			"user_id":    "test-user",
+			"tenant_id":  "tenant-1",
+			"input":      input,
+			"budget":     10.0,
+		}
+
+		body, _ := json.Marshal(req)
+		resp, err := client.Post(gatewayURL+"/infer", "application/json", bytes.NewBuffer(body))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		var result map[string]interface{}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || err != nil {
+			t.Fatalf("expected 200 with a JSON body, got %d (%v)", resp.StatusCode, err)
+		}
+
+		sum := md5.Sum([]byte(input))
+		want := fmt.Sprintf("prediction_%s_%s", result["tier"], hex.EncodeToString(sum[:])[:8])
+		if result["result"] != want {
+			t.Errorf("input %q: expected %s, got %v", input, want, result["result"])
+		}
+	}
+}
+
+func TestFeedback(t *testing.T) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	requestID := fmt.Sprintf("test-feedback-%d", time.Now().UnixNano())
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"request_id": requestID,
+		"user_id":    "test-user",
+		"tenant_id":  "tenant-1",
+		"input":      "label me",
+		"budget":     10.0,
+	})
+	resp, err := client.Post(gatewayURL+"/infer", "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	var served map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&served)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	feedback := func(fb map[string]interface{}) (int, map[string]interface{}) {
+		body, _ := json.Marshal(fb)
+		resp, err := client.Post(gatewayURL+"/feedback", "application/json", bytes.NewBuffer(body))
+		if err != nil {
+			t.Fatalf("feedback failed: %v", err)
+		}
+		defer resp.Body.Close()
+		var got map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&got)
+		return resp.StatusCode, got
+	}
+
+	if status, _ := feedback(map[string]interface{}{"request_id": requestID + "-unknown", "correct": true}); status != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown request_id, got %d", status)
+	}
+	if status, got := feedback(map[string]interface{}{"request_id": requestID, "label": "not the answer"}); status != http.StatusOK || got["correct"] != false {
+		t.Errorf("expected a wrong label to grade incorrect, got %d %v", status, got)
+	}
+	if status, got := feedback(map[string]interface{}{"request_id": requestID, "label": served["result"]}); status != http.StatusOK || got["correct"] != true {
+		t.Errorf("expected a duplicate label to regrade the request, got %d %v", status, got)
+	}
+	if status, _ := feedback(map[string]interface{}{"request_id": requestID, "tenant_id": "tenant-2", "correct": false}); status != http.StatusForbidden {
+		t.Errorf("expected 403 for another tenant's request, got %d", status)
+	}
+}